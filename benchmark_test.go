@@ -155,6 +155,73 @@ func Benchmark(b *testing.B) {
 	}
 }
 
+// BenchmarkQueryString compares decoding a raw query string through
+// url.ParseQuery and Parse, as ParseRequest used to, with ParseQueryString.
+func BenchmarkQueryString(b *testing.B) {
+	for _, tt := range tests {
+		raw := tt.values().Encode()
+
+		b.Run("ParseQuery/"+tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				values, err := url.ParseQuery(raw)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var f QFilter
+				if err := Parse(values, &f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run("Raw/"+tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				var f QFilter
+				if err := ParseQueryString(raw, &f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// QNested spreads the fields of QFilter over nested structs, which the decode
+// plan flattens into a single loop.
+type QNested struct {
+	Paging struct {
+		Page  int `qp:"page"`
+		Limit int `qp:"limit"`
+	}
+	Sort *struct {
+		SortBy string `qp:"sort_by"`
+		Order  string `qp:"order"`
+	}
+	Search struct {
+		Q         string  `qp:"q"`
+		Active    bool    `qp:"active"`
+		Threshold float32 `qp:"threshold"`
+		Extra     struct {
+			Foo string `qp:"foo"`
+			Bar string `qp:"bar"`
+			Buz string `qp:"buz"`
+		}
+	}
+}
+
+func BenchmarkNested(b *testing.B) {
+	b.ReportAllocs()
+	vals := maps.Clone(base)
+	b.ResetTimer()
+	for b.Loop() {
+		var f QNested
+		if err := Parse(vals, &f); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func makeIDSlice(n int) url.Values {
 	v := url.Values{}
 	for i := range n {
@@ -0,0 +1,152 @@
+package qparser
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"reflect"
+)
+
+// origin is the part of a request a field is bound to
+type origin uint8
+
+const (
+	originQuery origin = iota
+	originPath
+	originHeader
+	originCookie
+	numOrigins
+)
+
+// String returns the name of the origin, which is also the struct tag key
+// binding a field to it and the FieldError.Source of its failures.
+func (o origin) String() string {
+	switch o {
+	case originPath:
+		return "path"
+	case originHeader:
+		return "header"
+	case originCookie:
+		return "cookie"
+	}
+	return "query"
+}
+
+// fieldTag returns the tag binding field to its source: the Decoder's tag for
+// the query, or one of the path, header and cookie tags. A field can only be
+// bound to a single source.
+func (d *Decoder) fieldTag(field reflect.StructField) (string, origin, error) {
+	tag, org := field.Tag.Get(d.tagName), originQuery
+	for o := originPath; o < numOrigins; o++ {
+		t, ok := field.Tag.Lookup(o.String())
+		if !ok || o.String() == d.tagName {
+			continue
+		}
+		if tag != "" {
+			return "", originQuery, fmt.Errorf("%w: field is bound to more than one source", ErrInvalidTag)
+		}
+		tag, org = t, o
+	}
+	return tag, org, nil
+}
+
+// newBoundField describes a field bound to a path value, header or cookie.
+// Keys are used as is, without nested struct prefixes, and header names are
+// canonicalized like http.Header does.
+func (d *Decoder) newBoundField(field reflect.StructField, key string, opts tagOptions, origin origin) (fieldInfo, error) {
+	if key == "" {
+		return fieldInfo{}, fmt.Errorf("%w: %s tag requires a name", ErrInvalidTag, origin)
+	}
+	if opts.remain {
+		return fieldInfo{}, fmt.Errorf("%w: option \"remain\" is only supported on query fields", ErrInvalidTag)
+	}
+	if origin == originHeader {
+		key = textproto.CanonicalMIMEHeaderKey(key)
+	}
+
+	fi := fieldInfo{
+		name:     field.Name,
+		key:      key,
+		typ:      field.Type,
+		index:    field.Index,
+		required: opts.required,
+		nonempty: opts.nonempty,
+		origin:   origin,
+	}
+	fi.unmarshal, fi.elemUnmarshal = d.unmarshalKinds(field.Type)
+
+	typ := field.Type
+	if fi.unmarshal == unmarshalNone && (isFileType(typ) || d.isNestedStruct(typ) || isStringMap(typ) ||
+		typ.Kind() == reflect.Slice && d.isNestedStruct(typ.Elem())) {
+		return fieldInfo{}, fmt.Errorf("%w: %s tag requires a field decoded from a single key, got %v", ErrInvalidTag, origin, typ)
+	}
+
+	var err error
+	if fi.rules, err = compileRules(field.Type, opts.rules); err != nil {
+		return fieldInfo{}, err
+	}
+	if fi.enum, err = d.enumOf(field.Type, opts); err != nil {
+		return fieldInfo{}, err
+	}
+	if err = d.resolveDuplicates(&fi, opts); err != nil {
+		return fieldInfo{}, err
+	}
+	if err = d.resolveSplit(&fi, opts); err != nil {
+		return fieldInfo{}, err
+	}
+	fi.set = d.compileSetter(&fi)
+	if opts.hasDefault {
+		fi.defaults = splitDefault(opts.defaultValue, &fi)
+		if err := d.validateDefault(fi); err != nil {
+			return fieldInfo{}, err
+		}
+	}
+	return fi, nil
+}
+
+// requestSource is the query of a request along with the other parts of it
+// fields can be bound to, see Bind
+type requestSource struct {
+	KeySource
+	bound [numOrigins]Source // indexed by origin, the query is the embedded source
+}
+
+func newRequestSource(r *http.Request, query KeySource) *requestSource {
+	src := &requestSource{KeySource: query}
+	src.bound[originPath] = pathSource{r}
+	src.bound[originHeader] = HeaderSource(r.Header)
+	src.bound[originCookie] = cookieSource{r}
+	return src
+}
+
+// pathSource reads the wildcards of the route pattern that matched a request.
+// An empty path value is reported as absent, as r.PathValue cannot tell them
+// apart.
+type pathSource struct {
+	r *http.Request
+}
+
+func (s pathSource) Lookup(key string) ([]string, bool) {
+	if v := s.r.PathValue(key); v != "" {
+		return []string{v}, true
+	}
+	return nil, false
+}
+
+// cookieSource reads the cookies of a request, every cookie sent under a name
+// contributing one value
+type cookieSource struct {
+	r *http.Request
+}
+
+func (s cookieSource) Lookup(key string) ([]string, bool) {
+	cookies := s.r.CookiesNamed(key)
+	if len(cookies) == 0 {
+		return nil, false
+	}
+	vals := make([]string, len(cookies))
+	for i, c := range cookies {
+		vals[i] = c.Value
+	}
+	return vals, true
+}
@@ -0,0 +1,160 @@
+package qparser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type invoiceRequest struct {
+	ID       int64    `path:"id"`
+	Tenant   string   `header:"x-tenant,required"`
+	Langs    []string `header:"Accept-Language"`
+	Session  string   `cookie:"session"`
+	Theme    string   `cookie:"theme,default=light"`
+	Expand   []string `qp:"expand"`
+	Paginate struct {
+		Page  int    `qp:"page"`
+		Trace string `header:"X-Trace"`
+	} `qp:"p"`
+}
+
+// newBindRequest routes a request through a mux so path values are set
+func newBindRequest(t *testing.T, target string, setup func(r *http.Request)) *http.Request {
+	t.Helper()
+	var routed *http.Request
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /invoices/{id}", func(_ http.ResponseWriter, r *http.Request) {
+		routed = r
+	})
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if setup != nil {
+		setup(req)
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	require.NotNil(t, routed, "request not routed")
+	return routed
+}
+
+func TestBind(t *testing.T) {
+	t.Run("All-Sources", func(t *testing.T) {
+		req := newBindRequest(t, "/invoices/42?expand=lines,payer&p[page]=3", func(r *http.Request) {
+			r.Header.Set("X-Tenant", "acme")
+			r.Header.Add("Accept-Language", "en")
+			r.Header.Add("Accept-Language", "fr")
+			r.Header.Set("X-Trace", "abc")
+			r.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+		})
+
+		var got invoiceRequest
+		require.NoError(t, Bind(req, &got))
+		assert.Equal(t, int64(42), got.ID)
+		assert.Equal(t, "acme", got.Tenant)
+		assert.Equal(t, []string{"en", "fr"}, got.Langs)
+		assert.Equal(t, "s3cr3t", got.Session)
+		assert.Equal(t, "light", got.Theme)
+		assert.Equal(t, []string{"lines", "payer"}, got.Expand)
+		assert.Equal(t, 3, got.Paginate.Page)
+		assert.Equal(t, "abc", got.Paginate.Trace)
+	})
+
+	t.Run("Error-Source", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			target string
+			setup  func(r *http.Request)
+			field  string
+			source string
+			err    error
+		}{
+			{"Path", "/invoices/abc", func(r *http.Request) { r.Header.Set("X-Tenant", "acme") }, "invoiceRequest.ID", "path", ErrInvalidValue},
+			{"Header", "/invoices/1", nil, "invoiceRequest.Tenant", "header", ErrMissingValue},
+			{"Query", "/invoices/1?p[page]=x", func(r *http.Request) { r.Header.Set("X-Tenant", "acme") }, "invoiceRequest.Paginate.Page", "query", ErrInvalidValue},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got invoiceRequest
+				err := Bind(newBindRequest(t, tt.target, tt.setup), &got)
+				assert.ErrorIs(t, err, tt.err)
+
+				var fieldErr *FieldError
+				require.ErrorAs(t, err, &fieldErr)
+				assert.Equal(t, tt.field, fieldErr.FieldName)
+				assert.Equal(t, tt.source, fieldErr.Source)
+				assert.Contains(t, err.Error(), "from "+tt.source)
+			})
+		}
+	})
+
+	t.Run("Cookie-Error", func(t *testing.T) {
+		type params struct {
+			Visits int `cookie:"visits"`
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "visits", Value: "many"})
+
+		var got params
+		err := Bind(req, &got)
+		assert.EqualError(t, err, `failed to parse "params.Visits" from cookie: invalid value: many`)
+	})
+
+	t.Run("All-Errors", func(t *testing.T) {
+		req := newBindRequest(t, "/invoices/x", nil)
+
+		var got invoiceRequest
+		err := NewDecoder(WithAllErrors()).Bind(req, &got)
+
+		var fieldErrs FieldErrors
+		require.ErrorAs(t, err, &fieldErrs)
+		require.Len(t, fieldErrs, 2)
+		assert.Equal(t, "path", fieldErrs[0].Source)
+		assert.Equal(t, "header", fieldErrs[1].Source)
+	})
+
+	t.Run("Strict-Query-Only", func(t *testing.T) {
+		req := newBindRequest(t, "/invoices/1?expand=a&id=2", func(r *http.Request) {
+			r.Header.Set("X-Tenant", "acme")
+		})
+
+		var got invoiceRequest
+		err := NewDecoder(WithStrict()).Bind(req, &got)
+
+		var unknownErr *UnknownParameterError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, []string{"id"}, unknownErr.Keys)
+	})
+
+	t.Run("Parse-Skips-Bound-Fields", func(t *testing.T) {
+		var got invoiceRequest
+		require.NoError(t, Parse(url.Values{"id": {"1"}, "x-tenant": {"acme"}, "expand": {"a"}}, &got))
+		assert.Equal(t, invoiceRequest{Expand: []string{"a"}}, got)
+
+		values, err := Encode(invoiceRequest{ID: 1, Tenant: "acme", Expand: []string{"a"}})
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"expand": {"a"}, "p[page]": {"0"}}, values)
+	})
+
+	t.Run("Invalid-Tags", func(t *testing.T) {
+		type twoSources struct {
+			ID int `qp:"id" path:"id"`
+		}
+		type nested struct {
+			Inner struct {
+				A int `qp:"a"`
+			} `header:"inner"`
+		}
+		type noName struct {
+			Tenant string `header:""`
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		assert.ErrorIs(t, Bind(req, &twoSources{}), ErrInvalidTag)
+		assert.ErrorIs(t, Bind(req, &nested{}), ErrInvalidTag)
+		assert.ErrorIs(t, Bind(req, &noName{}), ErrInvalidTag)
+	})
+}
@@ -1,30 +1,89 @@
 package qparser
 
 import (
+	"fmt"
 	"reflect"
-	"sync"
-	"time"
+	"strings"
 )
 
-var structCache sync.Map
-
 type structInfo struct {
 	name                 string
 	fields               []fieldInfo
 	hasUnexportedWithTag bool
+	hasRemain            bool  // one of fields is a remain field
+	err                  error // invalid tag or default, reported on every parse
+
+	// steps is the decode plan compiled from fields, see compilePlan
+	steps []planStep
+}
+
+// structKey identifies cached metadata: the same struct type yields
+// different keys depending on the prefix it is nested under.
+type structKey struct {
+	typ    reflect.Type
+	prefix string
 }
 
 type fieldInfo struct {
 	name     string
-	tag      string
+	key      string // full query key, or the prefix of the children keys for nested fields
 	typ      reflect.Type
 	index    []int
 	isNested bool
+
+	// unmarshal is the decoding interface of the field type (through one pointer),
+	// elemUnmarshal the one of its slice element type (through one pointer).
+	unmarshal     unmarshalKind
+	elemUnmarshal unmarshalKind
+
+	// marshal and elemMarshal are the encoding counterparts used by Encoder.
+	marshal     marshalKind
+	elemMarshal marshalKind
+
+	// structElem is the element struct type of a []S or []*S field decoded
+	// from indexed keys, nil otherwise.
+	structElem reflect.Type
+
+	// mapValue describes the value type of a map[string]T field, nil otherwise.
+	// Entries are read from keys composed with the Decoder's Notation.
+	mapValue *fieldInfo
+
+	set   setter          // decodes the values of key, nil for nested and remain fields
+	rules []rule          // validation options checked once set succeeds, see compileRules
+	enum  *enumSet        // values accepted by the field or its elements, nil if any
+	dup   DuplicatePolicy // applied when the field takes a single value, see resolveDuplicates
+
+	// sep splits the values of a slice field into elements, unless nosplit
+	// is set, see resolveSplit.
+	sep     byte
+	nosplit bool
+
+	defaults  []string // raw default values used when key is absent, nil if none
+	required  bool
+	nonempty  bool
+	omitempty bool
+	join      bool
+
+	// remain marks a url.Values-like field receiving every query key that no
+	// other field in the struct tree consumes.
+	remain bool
+
+	// file marks a *multipart.FileHeader or []*multipart.FileHeader field,
+	// set from the files of a multipart form by ParseForm.
+	file bool
+
+	// origin is the part of the request the field is bound to by Bind.
+	// Fields bound outside the query are skipped by every other entry point.
+	origin origin
 }
 
-func getStructCache(rt reflect.Type) *structInfo {
+// getStructCache returns the metadata of rt with every key composed under
+// prefix, the key of the tagged nested struct field holding it ("" at the root).
+func (d *Decoder) getStructCache(rt reflect.Type, prefix string) *structInfo {
+	ck := structKey{typ: rt, prefix: prefix}
+
 	// Try to load from cache
-	if cached, ok := structCache.Load(rt); ok {
+	if cached, ok := d.cache.Load(ck); ok {
 		return cached.(*structInfo)
 	}
 
@@ -32,7 +91,11 @@ func getStructCache(rt reflect.Type) *structInfo {
 	info := &structInfo{name: rt.Name()}
 	for i := 0; i < rt.NumField(); i++ {
 		field := rt.Field(i)
-		tag := field.Tag.Get("qp")
+		tag, origin, err := d.fieldTag(field)
+		if err != nil {
+			info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+			break
+		}
 
 		if !field.IsExported() {
 			if tag != "" {
@@ -41,34 +104,316 @@ func getStructCache(rt reflect.Type) *structInfo {
 			continue
 		}
 
-		if tag != "" {
+		// "-" explicitly excludes the field
+		if tag == "-" {
+			continue
+		}
+
+		key, opts, err := parseTag(tag)
+		if err != nil {
+			info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+			break
+		}
+
+		if origin != originQuery {
+			fi, err := d.newBoundField(field, key, opts, origin)
+			if err != nil {
+				info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+				break
+			}
+			info.fields = append(info.fields, fi)
+			continue
+		}
+
+		if opts.remain {
+			if err := checkRemain(field.Type, key, opts); err != nil {
+				info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+				break
+			}
 			info.fields = append(info.fields, fieldInfo{
-				name:     field.Name,
-				tag:      tag,
-				typ:      field.Type,
-				index:    field.Index,
-				isNested: false,
+				name:   field.Name,
+				typ:    field.Type,
+				index:  field.Index,
+				remain: true,
 			})
-		} else {
-			// Check if this field is a nested struct (struct or pointer to struct)
-			fieldType := field.Type
-			if fieldType.Kind() == reflect.Ptr {
-				fieldType = fieldType.Elem()
+			info.hasRemain = true
+			continue
+		}
+
+		if key != "" && prefix != "" {
+			key = d.subKey(prefix, key)
+		}
+
+		if isFileType(field.Type) {
+			if err := checkFile(opts); err != nil {
+				info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+				break
 			}
-			if fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}) {
+			if key != "" {
 				info.fields = append(info.fields, fieldInfo{
-					name:     field.Name,
-					tag:      "",
-					typ:      field.Type, // Keep the original type (may be pointer)
-					index:    field.Index,
-					isNested: true,
+					name:      field.Name,
+					key:       key,
+					typ:       field.Type,
+					index:     field.Index,
+					required:  opts.required,
+					omitempty: opts.omitempty,
+					file:      true,
 				})
 			}
+			continue
 		}
+
+		if d.isNestedStruct(field.Type) {
+			// A tagged nested struct namespaces its children, an untagged one
+			// shares the keys of its parent
+			if opts != (tagOptions{}) {
+				info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name),
+					fmt.Errorf("%w: options are not supported on nested struct fields", ErrInvalidTag))
+				break
+			}
+			if key == "" {
+				key = prefix
+			}
+			info.fields = append(info.fields, fieldInfo{
+				name:     field.Name,
+				key:      key,
+				typ:      field.Type, // Keep the original type (may be pointer)
+				index:    field.Index,
+				isNested: true,
+			})
+			continue
+		}
+
+		if key != "" {
+			fi := fieldInfo{
+				name:      field.Name,
+				key:       key,
+				typ:       field.Type,
+				index:     field.Index,
+				isNested:  false,
+				required:  opts.required,
+				nonempty:  opts.nonempty,
+				omitempty: opts.omitempty,
+				join:      opts.join,
+			}
+			fi.unmarshal, fi.elemUnmarshal = d.unmarshalKinds(field.Type)
+			fi.marshal, fi.elemMarshal = marshalKinds(field.Type)
+			if isStringMap(field.Type) && fi.unmarshal == unmarshalNone {
+				// Enum options restrict the values of the map
+				if fi.mapValue, err = d.newValueInfo(field.Type.Elem(), opts); err != nil {
+					info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+					break
+				}
+			}
+			if field.Type.Kind() == reflect.Slice && fi.unmarshal == unmarshalNone && d.isNestedStruct(field.Type.Elem()) {
+				fi.structElem = field.Type.Elem()
+				if fi.structElem.Kind() == reflect.Ptr {
+					fi.structElem = fi.structElem.Elem()
+				}
+			}
+			if opts.rules != "" && (fi.mapValue != nil || fi.structElem != nil) {
+				info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name),
+					fmt.Errorf("%w: validation options require a field decoded from a single key, got %v", ErrInvalidTag, field.Type))
+				break
+			}
+			if fi.rules, err = compileRules(field.Type, opts.rules); err != nil {
+				info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+				break
+			}
+			if fi.mapValue == nil {
+				if fi.structElem != nil && (opts.hasEnum || opts.fold) {
+					err = fmt.Errorf("%w: enum options require a field decoded from a single key, got %v", ErrInvalidTag, field.Type)
+				} else if fi.enum, err = d.enumOf(field.Type, opts); err == nil {
+					err = d.resolveDuplicates(&fi, opts)
+				}
+				if err == nil {
+					err = d.resolveSplit(&fi, opts)
+				}
+				if err != nil {
+					info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+					break
+				}
+			}
+			fi.set = d.compileSetter(&fi)
+			if opts.hasDefault {
+				fi.defaults = splitDefault(opts.defaultValue, &fi)
+				if err := d.validateDefault(fi); err != nil {
+					info.err = wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.Name), err)
+					break
+				}
+			}
+			info.fields = append(info.fields, fi)
+		}
+	}
+
+	if info.err == nil && !info.hasUnexportedWithTag {
+		info.steps = d.compilePlan(rt, info)
 	}
 
 	// LoadOrStore handles race conditions atomically
 	// If another goroutine stored a value first, we return that instead
-	actual, _ := structCache.LoadOrStore(rt, info)
+	actual, _ := d.cache.LoadOrStore(ck, info)
 	return actual.(*structInfo)
 }
+
+// treeHasRemain reports whether rt, or any struct reached through its nested
+// and struct slice fields, holds a remain field. The answer is memoized per
+// type since it decides whether a decode tracks consumed keys.
+func (d *Decoder) treeHasRemain(rt reflect.Type) bool {
+	if cached, ok := d.remainCache.Load(rt); ok {
+		return cached.(bool)
+	}
+	found := d.walkTree(rt, "", make(map[structKey]bool), func(info *structInfo) bool {
+		return info.hasRemain
+	})
+	d.remainCache.Store(rt, found)
+	return found
+}
+
+// walkTree reports whether match holds for the metadata of rt or of a struct
+// reached through its nested and struct slice fields. seen guards against
+// self-referencing struct slices.
+func (d *Decoder) walkTree(rt reflect.Type, prefix string, seen map[structKey]bool, match func(*structInfo) bool) bool {
+	ck := structKey{typ: rt, prefix: prefix}
+	if seen[ck] {
+		return false
+	}
+	seen[ck] = true
+
+	info := d.getStructCache(rt, prefix)
+	if match(info) {
+		return true
+	}
+	for _, field := range info.fields {
+		switch {
+		case field.isNested:
+			ft := field.typ
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if d.walkTree(ft, field.key, seen, match) {
+				return true
+			}
+		case field.structElem != nil:
+			if d.walkTree(field.structElem, "", seen, match) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkRemain validates a field tagged with the remain option: it has no key
+// nor other options and is a map[string][]string, such as url.Values.
+func checkRemain(typ reflect.Type, key string, opts tagOptions) error {
+	if key != "" || opts != (tagOptions{remain: true}) {
+		return fmt.Errorf("%w: option \"remain\" takes no key nor other options", ErrInvalidTag)
+	}
+	if !isStringMap(typ) || typ.Elem() != reflect.TypeFor[[]string]() {
+		return fmt.Errorf("%w: option \"remain\" requires a url.Values or map[string][]string field, got %v", ErrInvalidTag, typ)
+	}
+	return nil
+}
+
+// checkFile validates the options of a file field: values cannot be split,
+// defaulted nor checked for blankness, only their presence can be required
+func checkFile(opts tagOptions) error {
+	if opts.hasDefault || opts.nonempty || opts.join || opts.rules != "" || opts.hasEnum || opts.fold || opts.hasDup || opts.hasSep || opts.nosplit {
+		return fmt.Errorf("%w: file fields only support the \"required\" and \"omitempty\" options", ErrInvalidTag)
+	}
+	return nil
+}
+
+// isNestedStruct reports whether typ is a struct, or pointer to struct, whose
+// fields are decoded individually rather than from a single value
+func (d *Decoder) isNestedStruct(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct && typ != timeType && unmarshalKindOf(typ) == unmarshalNone &&
+		d.converter(typ) == nil
+}
+
+// isStringMap reports whether typ is a map keyed by a string kind
+func isStringMap(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String
+}
+
+// newValueInfo describes a standalone value type, such as the values of a map
+// field, so it is decoded and encoded like a regular field. opts are the tag
+// options of the field holding the values, only its enum, dup and split
+// options apply.
+func (d *Decoder) newValueInfo(typ reflect.Type, opts tagOptions) (*fieldInfo, error) {
+	vi := &fieldInfo{typ: typ}
+	vi.unmarshal, vi.elemUnmarshal = d.unmarshalKinds(typ)
+	vi.marshal, vi.elemMarshal = marshalKinds(typ)
+	enum, err := d.enumOf(typ, opts)
+	if err != nil {
+		return nil, err
+	}
+	vi.enum = enum
+	if err := d.resolveDuplicates(vi, opts); err != nil {
+		return nil, err
+	}
+	if err := d.resolveSplit(vi, opts); err != nil {
+		return nil, err
+	}
+	vi.set = d.compileSetter(vi)
+	return vi, nil
+}
+
+// unmarshalKinds resolves the decoding interfaces of a field type and, when
+// the field does not implement one itself, of its slice element type. Types
+// with a converter are reported as implementing none, it takes precedence.
+func (d *Decoder) unmarshalKinds(typ reflect.Type) (field, elem unmarshalKind) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if d.converter(typ) != nil {
+		return unmarshalNone, unmarshalNone
+	}
+	if field = unmarshalKindOf(typ); field != unmarshalNone {
+		return field, unmarshalNone
+	}
+	if typ.Kind() == reflect.Slice {
+		elemType := typ.Elem()
+		if elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		if d.converter(elemType) == nil {
+			elem = unmarshalKindOf(elemType)
+		}
+	}
+	return field, elem
+}
+
+// splitDefault turns a raw default into the values fed to the field setter.
+// Slice fields (and pointers to slices) take '|' separated elements, unless
+// the field type decodes itself.
+func splitDefault(raw string, field *fieldInfo) []string {
+	typ := field.typ
+	if field.unmarshal != unmarshalNone {
+		return []string{raw}
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Slice {
+		return strings.Split(raw, "|")
+	}
+	return []string{raw}
+}
+
+// validateDefault decodes the default into a scratch value once, so a bad
+// default is reported when metadata is built rather than on every request.
+func (d *Decoder) validateDefault(field fieldInfo) error {
+	scratch := reflect.New(field.typ).Elem()
+	err := field.set(scratch, field.defaults)
+	if err == nil {
+		err = field.checkRules(scratch)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: default %q: %w", ErrInvalidTag, strings.Join(field.defaults, "|"), err)
+	}
+	return nil
+}
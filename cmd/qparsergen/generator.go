@@ -0,0 +1,584 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prawirdani/qparser"
+)
+
+const qparserPath = "github.com/prawirdani/qparser"
+
+// generate type-checks the package in dir and returns the formatted source of
+// the DecodeQuery methods of typeNames. Files previously generated by
+// qparsergen are left out, so stale output never gets in the way.
+func generate(dir string, typeNames []string) ([]byte, error) {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, imports: map[string]string{"net/url": "url", qparserPath: "qparser"}}
+	for _, name := range typeNames {
+		if err := g.generateType(name); err != nil {
+			return nil, err
+		}
+	}
+	return g.source()
+}
+
+// loadPackage parses and type-checks the non-test Go files of dir
+func loadPackage(dir string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if isGenerated(f) {
+			continue
+		}
+		files = append(files, f)
+	}
+
+	// Errors are tolerated: the package may only compile once the
+	// generated methods exist, e.g. when it asserts they are implemented.
+	conf := types.Config{Importer: importer.Default(), Error: func(error) {}}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+	return pkg, nil
+}
+
+// isGenerated reports whether f was written by qparsergen
+func isGenerated(f *ast.File) bool {
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			if c.Text == header {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const header = "// Code generated by qparsergen. DO NOT EDIT."
+
+// generator accumulates the methods of one output file
+type generator struct {
+	pkg     *types.Package
+	buf     bytes.Buffer
+	imports map[string]string // import path -> package name
+	typ     string            // type being generated, for error messages
+}
+
+// generateType writes the DecodeQuery method of the struct type name
+func (g *generator) generateType(name string) error {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("type %s not found in package %s", name, g.pkg.Name())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return fmt.Errorf("%s must be a non-generic named type", name)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("%s is not a struct type", name)
+	}
+
+	g.typ = name
+	fmt.Fprintf(&g.buf, "\n// DecodeQuery decodes values into dst, see qparser.QueryDecoder.\n")
+	fmt.Fprintf(&g.buf, "func (dst *%s) DecodeQuery(values url.Values) error {\n", name)
+	if err := g.generateFields(named, st, "dst", "", name); err != nil {
+		return err
+	}
+	fmt.Fprintf(&g.buf, "\treturn nil\n}\n")
+	return nil
+}
+
+// generateFields writes the decoding of every field of st, the underlying
+// struct of t, surrounded by the calls to the hooks of t. expr is the Go
+// expression of the struct value, prefix the key its fields are namespaced
+// under and path its FieldError name, as in the reflective decoder.
+func (g *generator) generateFields(t types.Type, st *types.Struct, expr, prefix, path string) error {
+	before, after := hooks(t, st)
+	if before {
+		fmt.Fprintf(&g.buf, "\t%s.BeforeParse()\n\n", expr)
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tags := reflect.StructTag(st.Tag(i))
+		tag := tags.Get(qparser.DefaultTagName)
+		fieldPath := path + "." + f.Name()
+		if tag != "" && hasBindTag(tags) {
+			return g.errorf(fieldPath, "%w: field is bound to more than one source", qparser.ErrInvalidTag)
+		}
+
+		if !f.Exported() {
+			if tag != "" {
+				return g.errorf(fieldPath, "%w", qparser.ErrUnexportedStruct)
+			}
+			continue
+		}
+		if tag == "-" {
+			continue
+		}
+
+		key, opts, err := parseTag(tag)
+		if err != nil {
+			return g.errorf(fieldPath, "%w", err)
+		}
+		if key != "" && prefix != "" {
+			key = prefix + "[" + key + "]"
+		}
+
+		fieldExpr := expr + "." + f.Name()
+		if isFileHeader(f.Type()) {
+			if key == "" {
+				continue
+			}
+			return g.errorf(fieldPath, "%w: file fields are not supported by qparsergen, decode the struct with qparser.ParseForm", qparser.ErrUnsupportedKind)
+		}
+		if nested := nestedStruct(f.Type()); nested != nil {
+			if opts != (tagOptions{}) {
+				return g.errorf(fieldPath, "%w: options are not supported on nested struct fields", qparser.ErrInvalidTag)
+			}
+			if key == "" {
+				key = prefix
+			}
+			nestedType := f.Type()
+			if ptr, ok := nestedType.(*types.Pointer); ok {
+				nestedType = ptr.Elem()
+				fmt.Fprintf(&g.buf, "\tif %s == nil {\n\t\t%s = new(%s)\n\t}\n\n", fieldExpr, fieldExpr, g.typeString(nestedType))
+			}
+			if err := g.generateFields(nestedType, nested, fieldExpr, key, fieldPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if key == "" {
+			continue
+		}
+		leaf, err := g.newLeaf(f.Type())
+		if err != nil {
+			return g.errorf(fieldPath, "%w", err)
+		}
+		if err := leaf.checkDefault(opts); err != nil {
+			return g.errorf(fieldPath, "%w", err)
+		}
+		g.generateLeaf(leaf, opts, fieldExpr, key, fieldPath)
+	}
+	if after {
+		fmt.Fprintf(&g.buf, "\tif err := %s.AfterParse(); err != nil {\n", expr)
+		fmt.Fprintf(&g.buf, "\t\treturn &qparser.FieldError{FieldName: %q, Err: err}\n\t}\n\n", path)
+	}
+	return nil
+}
+
+// hooks reports whether *t implements qparser.BeforeParser and
+// qparser.AfterParser, called around the decoding of the fields of st, its
+// underlying struct. Hooks promoted from an embedded nested struct are left
+// out, they are called around the fields of that struct.
+func hooks(t types.Type, st *types.Struct) (before, after bool) {
+	mset := types.NewMethodSet(types.NewPointer(t))
+	if sel := mset.Lookup(nil, "BeforeParse"); sel != nil && !promotedFromNested(sel, st) {
+		sig := sel.Type().(*types.Signature)
+		before = sig.Params().Len() == 0 && sig.Results().Len() == 0
+	}
+	if sel := mset.Lookup(nil, "AfterParse"); sel != nil && !promotedFromNested(sel, st) {
+		sig := sel.Type().(*types.Signature)
+		after = sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+			types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+	}
+	return before, after
+}
+
+// promotedFromNested reports whether the method sel is promoted from an
+// embedded field of st decoded as a nested struct
+func promotedFromNested(sel *types.Selection, st *types.Struct) bool {
+	index := sel.Index()
+	if len(index) < 2 {
+		return false
+	}
+	f := st.Field(index[0])
+	tag := reflect.StructTag(st.Tag(index[0])).Get(qparser.DefaultTagName)
+	return f.Exported() && tag != "-" && nestedStruct(f.Type()) != nil
+}
+
+// hasBindTag reports whether tags bind a field to a part of the request other
+// than the query, which only qparser.Bind decodes
+func hasBindTag(tags reflect.StructTag) bool {
+	for _, name := range []string{"path", "header", "cookie"} {
+		if _, ok := tags.Lookup(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedStruct returns the struct a field of type t decodes field by field,
+// nil when t is not a struct nor a pointer to one or decodes as a single value
+func nestedStruct(t types.Type) *types.Struct {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || isTime(t) || hasUnmarshaler(t) {
+		return nil
+	}
+	return st
+}
+
+func isTime(t types.Type) bool {
+	return isNamed(t, "time", "Time")
+}
+
+// isFileHeader reports whether t is *multipart.FileHeader, which ParseForm
+// sets from uploaded files
+func isFileHeader(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	return ok && isNamed(ptr.Elem(), "mime/multipart", "FileHeader")
+}
+
+// isNamed reports whether t is the type name declared in package path
+func isNamed(t types.Type, path, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == path && obj.Name() == name
+}
+
+// hasUnmarshaler reports whether t or *t decodes itself, which the reflective
+// decoder honors and generated code does not
+func hasUnmarshaler(t types.Type) bool {
+	if isTime(t) {
+		return false
+	}
+	mset := types.NewMethodSet(types.NewPointer(t))
+	return mset.Lookup(nil, "UnmarshalQuery") != nil || mset.Lookup(nil, "UnmarshalText") != nil
+}
+
+// isEnum reports whether t or *t implements qparser.Enum, whose values the
+// reflective decoder restricts and generated code does not
+func isEnum(t types.Type) bool {
+	mset := types.NewMethodSet(types.NewPointer(t))
+	sel := mset.Lookup(nil, "Values")
+	if sel == nil {
+		return false
+	}
+	sig := sel.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	slice, ok := sig.Results().At(0).Type().(*types.Slice)
+	return ok && types.Identical(slice.Elem(), types.Typ[types.String])
+}
+
+// shape is how a leaf field holds its scalar values
+type shape uint8
+
+const (
+	shapeValue    shape = iota // T
+	shapePtr                   // *T
+	shapeSlice                 // []T or []*T
+	shapePtrSlice              // *[]T or *[]*T
+)
+
+// leaf describes a field decoded from the values of a single key
+type leaf struct {
+	shape     shape
+	elemPtr   bool   // slice elements are pointers
+	base      string // type argument of qparser.ParseScalar, e.g. int64
+	scalar    string // Go type of the scalar, e.g. ID for type ID int64
+	sliceType string // Go type of the slice, for slice shapes
+}
+
+// newLeaf classifies a field type, reporting the ones generated code
+// cannot decode like the reflective decoder
+func (g *generator) newLeaf(t types.Type) (*leaf, error) {
+	l := &leaf{shape: shapeValue}
+	if ptr, ok := t.(*types.Pointer); ok {
+		l.shape = shapePtr
+		t = ptr.Elem()
+	}
+	if s, ok := t.Underlying().(*types.Slice); ok && !hasUnmarshaler(t) {
+		if l.shape == shapePtr {
+			l.shape = shapePtrSlice
+		} else {
+			l.shape = shapeSlice
+		}
+		l.sliceType = g.typeString(t)
+		t = s.Elem()
+		if ptr, ok := t.(*types.Pointer); ok {
+			l.elemPtr = true
+			t = ptr.Elem()
+		}
+	}
+
+	base, ok := scalarBase(t)
+	if !ok || hasUnmarshaler(t) || isEnum(t) {
+		return nil, fmt.Errorf("%w: %s is not supported by qparsergen, decode the struct with qparser.Parse",
+			qparser.ErrUnsupportedKind, types.TypeString(t, (*types.Package).Name))
+	}
+	if base == "time.Time" {
+		g.imports["time"] = "time"
+	}
+	l.base, l.scalar = base, g.typeString(t)
+	return l, nil
+}
+
+// scalarBase returns the qparser.Scalar type t decodes as
+func scalarBase(t types.Type) (string, bool) {
+	if isTime(t) {
+		return "time.Time", true
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return "", false
+	}
+	switch basic.Kind() {
+	case types.String, types.Bool,
+		types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64,
+		types.Float32, types.Float64:
+		return basic.Name(), true
+	}
+	return "", false
+}
+
+// checkDefault decodes the default of a field once, like the reflective
+// decoder does when it builds struct metadata
+func (l *leaf) checkDefault(opts tagOptions) error {
+	if !opts.hasDefault {
+		return nil
+	}
+
+	var elems []string
+	switch {
+	case l.shape >= shapeSlice:
+		elems = qparser.SplitValues(strings.Split(opts.defaultValue, "|"))
+	case l.shape == shapePtr && opts.defaultValue == "":
+	default:
+		elems = []string{opts.defaultValue}
+	}
+	for i, elem := range elems {
+		err := parseBase(l.base, elem)
+		if err != nil && l.shape >= shapeSlice {
+			err = fmt.Errorf("element [%d]: %w", i, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: default %q: %w", qparser.ErrInvalidTag, opts.defaultValue, err)
+		}
+	}
+	return nil
+}
+
+// parseBase decodes val as the qparser.Scalar named base
+func parseBase(base, val string) error {
+	var err error
+	switch base {
+	case "bool":
+		_, err = qparser.ParseScalar[bool](val)
+	case "int":
+		_, err = qparser.ParseScalar[int](val)
+	case "int8":
+		_, err = qparser.ParseScalar[int8](val)
+	case "int16":
+		_, err = qparser.ParseScalar[int16](val)
+	case "int32":
+		_, err = qparser.ParseScalar[int32](val)
+	case "int64":
+		_, err = qparser.ParseScalar[int64](val)
+	case "uint":
+		_, err = qparser.ParseScalar[uint](val)
+	case "uint8":
+		_, err = qparser.ParseScalar[uint8](val)
+	case "uint16":
+		_, err = qparser.ParseScalar[uint16](val)
+	case "uint32":
+		_, err = qparser.ParseScalar[uint32](val)
+	case "uint64":
+		_, err = qparser.ParseScalar[uint64](val)
+	case "float32":
+		_, err = qparser.ParseScalar[float32](val)
+	case "float64":
+		_, err = qparser.ParseScalar[float64](val)
+	case "time.Time":
+		_, err = qparser.ParseScalar[time.Time](val)
+	}
+	return err
+}
+
+// generateLeaf writes the lookup, presence checks and decoding of a leaf field
+func (g *generator) generateLeaf(l *leaf, opts tagOptions, expr, key, path string) {
+	qkey, qpath := strconv.Quote(key), strconv.Quote(path)
+	fieldErr := func(err string) string {
+		return fmt.Sprintf("return &qparser.FieldError{FieldName: %s, Err: %s}", qpath, err)
+	}
+	emptyErr := fieldErr(fmt.Sprintf("fmt.Errorf(\"%%w: %%q\", qparser.ErrEmptyValue, %s)", qkey))
+
+	fmt.Fprintf(&g.buf, "\t// %s\n", path)
+	switch {
+	case opts.required || opts.hasDefault:
+		fmt.Fprintf(&g.buf, "\t{\n\t\tvals, ok := values[%s]\n\t\tif !ok {\n", qkey)
+		if opts.required {
+			fmt.Fprintf(&g.buf, "\t\t\t%s\n", fieldErr(fmt.Sprintf("fmt.Errorf(\"%%w: %%q\", qparser.ErrMissingValue, %s)", qkey)))
+		} else {
+			fmt.Fprintf(&g.buf, "\t\t\tvals = %s\n", defaultLiteral(l, opts.defaultValue))
+		}
+		if opts.nonempty {
+			fmt.Fprintf(&g.buf, "\t\t} else if qparser.IsBlank(vals) {\n\t\t\t%s\n", emptyErr)
+		}
+		fmt.Fprintf(&g.buf, "\t\t}\n")
+	default:
+		fmt.Fprintf(&g.buf, "\tif vals, ok := values[%s]; ok {\n", qkey)
+		if opts.nonempty {
+			fmt.Fprintf(&g.buf, "\t\tif qparser.IsBlank(vals) {\n\t\t\t%s\n\t\t}\n", emptyErr)
+		}
+	}
+	if opts.required || opts.nonempty {
+		g.imports["fmt"] = "fmt"
+	}
+
+	switch l.shape {
+	case shapeValue:
+		fmt.Fprintf(&g.buf, "\t\tif len(vals) > 0 {\n")
+		g.generateScalar(l, "vals[0]", expr, false, fieldErr("err"))
+		fmt.Fprintf(&g.buf, "\t\t}\n")
+	case shapePtr:
+		fmt.Fprintf(&g.buf, "\t\tif len(vals) > 0 && vals[0] != \"\" {\n")
+		g.generateScalar(l, "vals[0]", expr, true, fieldErr("err"))
+		fmt.Fprintf(&g.buf, "\t\t}\n")
+	default:
+		ref := ""
+		if l.shape == shapePtrSlice {
+			ref = "&"
+		}
+		fmt.Fprintf(&g.buf, "\t\tif elems := qparser.SplitValues(vals); len(elems) > 0 {\n")
+		if l.sliceType == "[]string" {
+			// SplitValues already returns a fresh []string
+			fmt.Fprintf(&g.buf, "\t\t\t%s = %selems\n\t\t}\n\t}\n\n", expr, ref)
+			return
+		}
+		g.imports["fmt"] = "fmt"
+		fmt.Fprintf(&g.buf, "\t\t\ts := make(%s, len(elems))\n\t\t\tfor i, elem := range elems {\n", l.sliceType)
+		g.generateScalar(l, "elem", "s[i]", l.elemPtr, fieldErr("fmt.Errorf(\"element [%d]: %w\", i, err)"))
+		fmt.Fprintf(&g.buf, "\t\t\t}\n\t\t\t%s = %ss\n\t\t}\n", expr, ref)
+	}
+	fmt.Fprintf(&g.buf, "\t}\n\n")
+}
+
+// generateScalar writes the decoding of the string expression src into the
+// assignable expression dst, through a new pointer when ptr is set. ret is
+// the statement reporting a decoding error held by err.
+func (g *generator) generateScalar(l *leaf, src, dst string, ptr bool, ret string) {
+	value := "v"
+	if l.base == "string" {
+		value = src
+	} else {
+		fmt.Fprintf(&g.buf, "v, err := qparser.ParseScalar[%s](%s)\nif err != nil {\n%s\n}\n", l.base, src, ret)
+	}
+	if l.scalar != l.base {
+		value = l.scalar + "(" + value + ")"
+	}
+	switch {
+	case !ptr:
+		fmt.Fprintf(&g.buf, "%s = %s\n", dst, value)
+	case value == "v":
+		fmt.Fprintf(&g.buf, "%s = &v\n", dst)
+	default:
+		fmt.Fprintf(&g.buf, "p := %s\n%s = &p\n", value, dst)
+	}
+}
+
+// defaultLiteral returns the []string literal of a default value, split on
+// '|' for slice fields like the reflective decoder does
+func defaultLiteral(l *leaf, raw string) string {
+	vals := []string{raw}
+	if l.shape >= shapeSlice {
+		vals = strings.Split(raw, "|")
+	}
+	quoted := make([]string, len(vals))
+	for i, v := range vals {
+		quoted[i] = strconv.Quote(v)
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
+
+// typeString renders t as written in the generated file, recording imports
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+func (g *generator) errorf(path, format string, args ...any) error {
+	return fmt.Errorf("%s: "+format, append([]any{path}, args...)...)
+}
+
+// source assembles and formats the output file
+func (g *generator) source() ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\npackage %s\n\nimport (\n", header, g.pkg.Name())
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	slices.SortFunc(paths, func(a, b string) int {
+		// Standard library first, as gofmt users expect
+		if aStd, bStd := isStd(a), isStd(b); aStd != bStd {
+			if aStd {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	for i, path := range paths {
+		if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+			out.WriteByte('\n')
+		}
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n")
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, errors.Join(errors.New("formatting generated code"), err)
+	}
+	return src, nil
+}
+
+// isStd reports whether path looks like a standard library import path
+func isStd(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prawirdani/qparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateGolden(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	want, err := os.ReadFile(filepath.Join(dir, "search_qp.go"))
+	require.NoError(t, err)
+
+	got, err := generate(dir, []string{"Search", "Order"})
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "internal/gentest is stale, run go generate ./...")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		typ  string
+		err  error
+		msg  string
+	}{
+		{
+			name: "missing type",
+			src:  `type T struct{}`,
+			typ:  "Missing",
+			msg:  "type Missing not found in package p",
+		},
+		{
+			name: "not a struct",
+			src:  `type T int`,
+			typ:  "T",
+			msg:  "T is not a struct type",
+		},
+		{
+			name: "map field",
+			src:  `type T struct { M map[string]string ` + "`qp:\"m\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrUnsupportedKind,
+			msg:  "T.M: unsupported kind: map[string]string is not supported by qparsergen, decode the struct with qparser.Parse",
+		},
+		{
+			name: "struct slice",
+			src:  `type E struct { A int ` + "`qp:\"a\"`" + ` }; type T struct { S []E ` + "`qp:\"s\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrUnsupportedKind,
+		},
+		{
+			name: "unmarshaler",
+			src:  `type U struct{}; func (*U) UnmarshalText([]byte) error { return nil }; type T struct { U U ` + "`qp:\"u\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrUnsupportedKind,
+		},
+		{
+			name: "file field",
+			src:  `import "mime/multipart"; type T struct { F *multipart.FileHeader ` + "`qp:\"f\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrUnsupportedKind,
+		},
+		{
+			name: "remain",
+			src:  `type T struct { R map[string][]string ` + "`qp:\",remain\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrInvalidTag,
+		},
+		{
+			name: "validation option",
+			src:  `type T struct { N int ` + "`qp:\"n,min=1\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrInvalidTag,
+			msg:  `T.N: invalid tag: option "min" is not supported by qparsergen`,
+		},
+		{
+			name: "enum option",
+			src:  `type T struct { S string ` + "`qp:\"s,enum=a|b\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrInvalidTag,
+		},
+		{
+			name: "dup option",
+			src:  `type T struct { S string ` + "`qp:\"s,dup=error\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrInvalidTag,
+			msg:  `T.S: invalid tag: option "dup" is not supported by qparsergen`,
+		},
+		{
+			name: "sep option",
+			src:  `type T struct { S []string ` + "`qp:\"s,sep=|\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrInvalidTag,
+			msg:  `T.S: invalid tag: option "sep" is not supported by qparsergen`,
+		},
+		{
+			name: "enum type",
+			src:  `type Dir string; func (Dir) Values() []string { return []string{"asc"} }; type T struct { D []Dir ` + "`qp:\"d\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrUnsupportedKind,
+		},
+		{
+			name: "unexported tagged field",
+			src:  `type T struct { a int ` + "`qp:\"a\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrUnexportedStruct,
+		},
+		{
+			name: "invalid default",
+			src:  `type T struct { N []int ` + "`qp:\"n,default=1|x\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrInvalidTag,
+			msg:  `T.N: invalid tag: default "1|x": element [1]: invalid value: x`,
+		},
+		{
+			name: "options on nested struct",
+			src:  `type N struct{ A int ` + "`qp:\"a\"`" + ` }; type T struct { N N ` + "`qp:\"n,required\"`" + ` }`,
+			typ:  "T",
+			err:  qparser.ErrInvalidTag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "p.go"), []byte("package p\n\n"+tt.src+"\n"), 0o644))
+
+			_, err := generate(dir, []string{tt.typ})
+			require.Error(t, err)
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), "got %v", err)
+			}
+			if tt.msg != "" {
+				assert.EqualError(t, err, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Command qparsergen generates reflection-free query decoders for qp-tagged
+// structs.
+//
+// For every named struct type it is given, qparsergen writes a DecodeQuery
+// method implementing qparser.QueryDecoder. The method decodes url.Values like
+// qparser.Parse does with the default Decoder, which calls it directly:
+// same keys, defaults, element splitting, time formats and FieldError names.
+//
+// Usage, from a file of the package declaring the types:
+//
+//	//go:generate go run github.com/prawirdani/qparser/cmd/qparsergen -type Order,Filter
+//
+// Flags:
+//
+//	-type    comma-separated list of struct type names, required
+//	-output  output file, <first type in lower case>_qp.go by default
+//
+// Supported fields are strings, booleans, integers, floats and time.Time, and
+// named types based on them, as values, pointers, slices, slices of pointers
+// and pointers to slices, plus nested structs and pointers to nested structs.
+// The default, required and nonempty tag options are honored. Other fields,
+// such as maps, slices of structs or types implementing qparser.Unmarshaler or
+// encoding.TextUnmarshaler, are reported as errors: decode such structs with
+// qparser.Parse.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("qparsergen: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of struct type names")
+	output := flag.String("output", "", "output file name; default <type>_qp.go")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: qparsergen -type T[,T...] [-output file] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	names := strings.Split(*typeNames, ",")
+	src, err := generate(dir, names)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	name := *output
+	if name == "" {
+		name = strings.ToLower(names[0]) + "_qp.go"
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	if err := os.WriteFile(name, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
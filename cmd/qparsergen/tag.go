@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prawirdani/qparser"
+)
+
+// tagOptions holds the tag options generated code honors. omitempty and join
+// only affect encoding and are accepted without effect.
+type tagOptions struct {
+	hasDefault   bool
+	defaultValue string
+	required     bool
+	nonempty     bool
+}
+
+// parseTag splits a struct tag value into the query key and its options,
+// rejecting what qparser rejects and what qparsergen does not support.
+func parseTag(tag string) (string, tagOptions, error) {
+	var opts tagOptions
+	key, rest, _ := strings.Cut(tag, ",")
+
+	for rest != "" {
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+
+		name, value, hasValue := strings.Cut(opt, "=")
+		switch name {
+		case "default":
+			if !hasValue {
+				return "", opts, fmt.Errorf("%w: option %q requires a value", qparser.ErrInvalidTag, name)
+			}
+			opts.hasDefault = true
+			opts.defaultValue = value
+		case "required":
+			opts.required = true
+		case "nonempty":
+			opts.nonempty = true
+		case "omitempty", "join":
+		case "remain", "min", "max", "len", "oneof", "regex", "nonzero", "enum", "fold", "dup", "sep", "nosplit":
+			return "", opts, fmt.Errorf("%w: option %q is not supported by qparsergen", qparser.ErrInvalidTag, name)
+		default:
+			return "", opts, fmt.Errorf("%w: unknown option %q", qparser.ErrInvalidTag, opt)
+		}
+	}
+
+	if opts.required && opts.hasDefault {
+		return "", opts, fmt.Errorf("%w: options \"required\" and \"default\" are mutually exclusive", qparser.ErrInvalidTag)
+	}
+
+	return key, opts, nil
+}
@@ -0,0 +1,117 @@
+package qparser
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryDecoder is implemented by the DecodeQuery methods cmd/qparsergen
+// generates. They decode values without reflection, with the semantics Parse
+// has under the default configuration.
+//
+// Parse, ParseQueryString, ParseRequest and ParseURL call DecodeQuery
+// directly when dst implements QueryDecoder, unless the Decoder's options
+// change how values are decoded (see WithoutGenerated).
+type QueryDecoder interface {
+	DecodeQuery(values url.Values) error
+}
+
+// Scalar lists the types ParseScalar decodes.
+type Scalar interface {
+	string | bool |
+		int | int8 | int16 | int32 | int64 |
+		uint | uint8 | uint16 | uint32 | uint64 |
+		float32 | float64 |
+		time.Time
+}
+
+// ParseScalar decodes a single query value into T, reporting the same errors
+// as a field of type T decoded by Parse. It is used by generated decoders.
+func ParseScalar[T Scalar](val string) (T, error) {
+	var v T
+	var err error
+	switch p := any(&v).(type) {
+	case *string:
+		*p = val
+	case *bool:
+		if *p, err = strconv.ParseBool(val); err != nil {
+			err = ErrInvalidValue
+		}
+	case *int:
+		*p, err = parseInt[int](val, strconv.IntSize)
+	case *int8:
+		*p, err = parseInt[int8](val, 8)
+	case *int16:
+		*p, err = parseInt[int16](val, 16)
+	case *int32:
+		*p, err = parseInt[int32](val, 32)
+	case *int64:
+		*p, err = parseInt[int64](val, 64)
+	case *uint:
+		*p, err = parseUint[uint](val, strconv.IntSize)
+	case *uint8:
+		*p, err = parseUint[uint8](val, 8)
+	case *uint16:
+		*p, err = parseUint[uint16](val, 16)
+	case *uint32:
+		*p, err = parseUint[uint32](val, 32)
+	case *uint64:
+		*p, err = parseUint[uint64](val, 64)
+	case *float32:
+		var f float64
+		f, err = strconv.ParseFloat(val, 32)
+		*p = float32(f)
+	case *float64:
+		*p, err = strconv.ParseFloat(val, 64)
+	case *time.Time:
+		*p, err = parseTime(val)
+		return v, err
+	}
+	if _, ok := err.(*strconv.NumError); ok {
+		err = strconvNumError(err, val)
+	}
+	return v, err
+}
+
+func parseInt[T int | int8 | int16 | int32 | int64](val string, bits int) (T, error) {
+	n, err := strconv.ParseInt(val, 10, bits)
+	return T(n), err
+}
+
+func parseUint[T uint | uint8 | uint16 | uint32 | uint64](val string, bits int) (T, error) {
+	n, err := strconv.ParseUint(val, 10, bits)
+	return T(n), err
+}
+
+// SplitValues splits every value on DefaultSeparator into the elements a
+// slice field decoded by Parse receives: surrounding whitespace is trimmed,
+// escaped separators are kept and empty elements are dropped. It is used by generated decoders.
+func SplitValues(vals []string) []string {
+	n := 0
+	for _, v := range vals {
+		n += strings.Count(v, string(DefaultSeparator)) + 1
+	}
+	elems := make([]string, 0, n)
+	for _, v := range vals {
+		for i := 0; i < len(v); {
+			elem, next, escaped := cutElem(v, i, DefaultSeparator)
+			i = next
+			if elem == "" {
+				continue
+			}
+			if escaped {
+				elem = unescapeElem(elem, DefaultSeparator)
+			}
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
+
+// IsBlank reports whether every value is empty or whitespace only, the
+// condition the nonempty tag option rejects. It is used by generated decoders.
+func IsBlank(vals []string) bool {
+	return isBlank(vals)
+}
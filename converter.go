@@ -0,0 +1,139 @@
+package qparser
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// converters holds the converters registered with RegisterConverter. The map
+// is replaced, never modified, so decoders read it without locking.
+var (
+	converters   atomic.Pointer[map[reflect.Type]scalarSetter]
+	convertersMu sync.Mutex // serializes RegisterConverter
+)
+
+// RegisterConverter makes every Decoder decode values of type T with fn, e.g.
+// third-party types such as decimal.Decimal or uuid.UUID that cannot
+// implement Unmarshaler. Fields of type T, *T and []T, map values and
+// defaults all go through fn, one raw value at a time. Registering T again
+// replaces its converter.
+//
+// A converter takes precedence over the built-in conversions and over the
+// Unmarshaler and encoding.TextUnmarshaler implementations of T, and struct
+// types with a converter are decoded from a single key rather than as nested
+// structs. Errors returned by fn are passed through, wrapped in a FieldError.
+//
+// Generated DecodeQuery methods know nothing of converters: structs holding a
+// field converted by fn, directly or through nested structs, are decoded with
+// reflection instead. Other structs keep their generated methods.
+//
+// Decoders cache the conversions of a struct type the first time they decode
+// it: register converters during initialization, before decoding. The
+// package-level functions pick up converters registered later. It panics if
+// T is a pointer or slice type, whose conversion follows from the one of
+// their element.
+func RegisterConverter[T any](fn func(string) (T, error)) {
+	typ := reflect.TypeFor[T]()
+	set := newConverter(typ, fn)
+
+	convertersMu.Lock()
+	next := make(map[reflect.Type]scalarSetter)
+	if cur := converters.Load(); cur != nil {
+		for t, s := range *cur {
+			next[t] = s
+		}
+	}
+	next[typ] = set
+	converters.Store(&next)
+	convertersMu.Unlock()
+
+	defaultDecoder.cache.Clear()
+	defaultDecoder.remainCache.Clear()
+	defaultDecoder.converterCache.Clear()
+}
+
+// WithConverter makes the Decoder decode values of type T with fn, taking
+// precedence over a converter registered for T with RegisterConverter. See
+// RegisterConverter for how converters apply. It panics if T is a pointer or
+// slice type.
+func WithConverter[T any](fn func(string) (T, error)) Option {
+	typ := reflect.TypeFor[T]()
+	set := newConverter(typ, fn)
+	return func(d *Decoder) {
+		if d.converters == nil {
+			d.converters = make(map[reflect.Type]scalarSetter)
+		}
+		d.converters[typ] = set
+	}
+}
+
+// newConverter wraps fn into the scalarSetter of typ, the type of T
+func newConverter[T any](typ reflect.Type, fn func(string) (T, error)) scalarSetter {
+	if typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		panic(fmt.Sprintf("qparser: cannot register a converter for %v, register one for its element type", typ))
+	}
+	return func(fv reflect.Value, val string) error {
+		v, err := fn(val)
+		if err != nil {
+			return err
+		}
+		*fv.Addr().Interface().(*T) = v
+		return nil
+	}
+}
+
+// converter returns the converter of typ, nil if it has none
+func (d *Decoder) converter(typ reflect.Type) scalarSetter {
+	if set, ok := d.converters[typ]; ok {
+		return set
+	}
+	if global := converters.Load(); global != nil {
+		return (*global)[typ]
+	}
+	return nil
+}
+
+// hasConverters reports whether any converter applies to the Decoder
+func (d *Decoder) hasConverters() bool {
+	return len(d.converters) > 0 || converters.Load() != nil
+}
+
+// treeUsesConverter reports whether a field of rt, or of a struct reached
+// through its nested and struct slice fields, is decoded by a converter.
+// Generated decoders ignore converters, so they are skipped for such types
+// only. The answer is memoized per type like treeHasRemain.
+func (d *Decoder) treeUsesConverter(rt reflect.Type) bool {
+	if !d.hasConverters() {
+		return false
+	}
+	if cached, ok := d.converterCache.Load(rt); ok {
+		return cached.(bool)
+	}
+	found := d.walkTree(rt, "", make(map[structKey]bool), func(info *structInfo) bool {
+		return slices.ContainsFunc(info.fields, d.usesConverter)
+	})
+	d.converterCache.Store(rt, found)
+	return found
+}
+
+// usesConverter reports whether field, its element or its map values are
+// decoded by a converter
+func (d *Decoder) usesConverter(field fieldInfo) bool {
+	typ := field.typ
+	if field.mapValue != nil {
+		typ = field.mapValue.typ
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+	}
+	return d.converter(typ) != nil
+}
@@ -0,0 +1,174 @@
+package qparser
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cents stands for a third-party struct type that cannot implement Unmarshaler
+type cents struct {
+	N int64
+}
+
+// parseCents converts "12.34" into cents
+func parseCents(s string) (cents, error) {
+	units, frac, _ := strings.Cut(s, ".")
+	u, err := strconv.ParseInt(units, 10, 64)
+	if err != nil {
+		return cents{}, ErrInvalidValue
+	}
+	c, err := strconv.ParseInt(frac, 10, 64)
+	if frac != "" && err != nil {
+		return cents{}, ErrInvalidValue
+	}
+	return cents{N: u*100 + c}, nil
+}
+
+// registerConverter is RegisterConverter undone at the end of the test
+func registerConverter[T any](t *testing.T, fn func(string) (T, error)) {
+	t.Helper()
+	RegisterConverter(fn)
+	t.Cleanup(func() {
+		converters.Store(nil)
+		defaultDecoder.cache.Clear()
+		defaultDecoder.remainCache.Clear()
+		defaultDecoder.converterCache.Clear()
+	})
+}
+
+func TestConverter(t *testing.T) {
+	type order struct {
+		Total    cents            `qp:"total"`
+		Discount *cents           `qp:"discount"`
+		Lines    []cents          `qp:"lines"`
+		Refunds  []*cents         `qp:"refunds"`
+		Fees     map[string]cents `qp:"fee"`
+		Shipping cents            `qp:"shipping,default=4.99"`
+	}
+
+	t.Run("Register", func(t *testing.T) {
+		type invoice struct {
+			Total cents `qp:"total"`
+		}
+		var inv invoice
+		require.NoError(t, Parse(url.Values{"total": {"1"}}, &inv))
+		assert.Zero(t, inv.Total, "decoded as a nested struct")
+
+		registerConverter(t, parseCents)
+		require.NoError(t, Parse(url.Values{"total": {"1"}}, &inv))
+		assert.Equal(t, cents{100}, inv.Total, "cached metadata is rebuilt")
+
+		var got order
+		require.NoError(t, Parse(url.Values{
+			"total":     {"12.34"},
+			"discount":  {"1"},
+			"lines":     {"1.50,2"},
+			"refunds":   {"3"},
+			"fee[card]": {"0.30"},
+		}, &got))
+		assert.Equal(t, order{
+			Total:    cents{1234},
+			Discount: &cents{100},
+			Lines:    []cents{{150}, {200}},
+			Refunds:  []*cents{{300}},
+			Fees:     map[string]cents{"card": {30}},
+			Shipping: cents{499},
+		}, got)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		registerConverter(t, parseCents)
+
+		var got order
+		err := Parse(url.Values{"lines": {"1,x"}}, &got)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "order.Lines", fieldErr.FieldName)
+
+		errOdd := errors.New("odd")
+		d := NewDecoder(WithConverter(func(s string) (int, error) {
+			n, _ := strconv.Atoi(s)
+			if n%2 != 0 {
+				return 0, errOdd
+			}
+			return n, nil
+		}))
+		var params struct {
+			N int `qp:"n"`
+		}
+		assert.ErrorIs(t, d.Parse(url.Values{"n": {"3"}}, &params), errOdd)
+		require.NoError(t, d.Parse(url.Values{"n": {"4"}}, &params))
+		assert.Equal(t, 4, params.N)
+	})
+
+	t.Run("Bad-Default", func(t *testing.T) {
+		type params struct {
+			Total cents `qp:"total,default=free"`
+		}
+		var got params
+		err := NewDecoder(WithConverter(parseCents)).Parse(url.Values{}, &got)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+	})
+
+	t.Run("Precedence", func(t *testing.T) {
+		registerConverter(t, parseCents)
+		d := NewDecoder(WithConverter(func(s string) (cents, error) {
+			return cents{N: int64(len(s))}, nil
+		}))
+
+		var got order
+		require.NoError(t, d.Parse(url.Values{"total": {"abc"}}, &got))
+		assert.Equal(t, cents{3}, got.Total, "decoder converter wins over the registered one")
+
+		type params struct {
+			Status status `qp:"status"`
+		}
+		var p params
+		require.NoError(t, NewDecoder(WithConverter(func(s string) (status, error) {
+			n, err := strconv.Atoi(s)
+			return status(n), err
+		})).Parse(url.Values{"status": {"2"}}, &p))
+		assert.Equal(t, statusArchived, p.Status, "converter wins over UnmarshalText")
+	})
+
+	t.Run("Used-By-Type", func(t *testing.T) {
+		type line struct {
+			Amount *cents `qp:"amount"`
+		}
+		type plain struct {
+			Page int `qp:"page"`
+		}
+		type nested struct {
+			Plain plain
+			Lines []line `qp:"lines"`
+		}
+		type fees struct {
+			Fees map[string][]cents `qp:"fee"`
+		}
+
+		d := NewDecoder(WithConverter(parseCents))
+		assert.True(t, d.treeUsesConverter(reflect.TypeFor[order]()))
+		assert.True(t, d.treeUsesConverter(reflect.TypeFor[nested]()), "through a struct slice")
+		assert.True(t, d.treeUsesConverter(reflect.TypeFor[fees]()), "through map values")
+		assert.False(t, d.treeUsesConverter(reflect.TypeFor[plain]()))
+		assert.False(t, NewDecoder().treeUsesConverter(reflect.TypeFor[order]()))
+	})
+
+	t.Run("Invalid-Type", func(t *testing.T) {
+		assert.Panics(t, func() {
+			WithConverter(func(string) (*cents, error) { return nil, nil })
+		})
+		assert.Panics(t, func() {
+			RegisterConverter(func(string) ([]cents, error) { return nil, nil })
+		})
+	})
+}
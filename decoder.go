@@ -0,0 +1,388 @@
+package qparser
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+const (
+	// DefaultTagName is the struct tag key read by a Decoder unless overridden with WithTagName.
+	DefaultTagName = "qp"
+
+	// DefaultMaxSliceIndex is the highest element index accepted for slices of
+	// structs (items[1000][name]) unless overridden with WithMaxSliceIndex.
+	DefaultMaxSliceIndex = 1000
+
+	// DefaultSeparator is the byte used to split multiple values packed into a single
+	// query value (e.g. "a,b,c") unless overridden with WithSeparator.
+	DefaultSeparator = ','
+
+	// DefaultMaxFormSize is the largest request body, in bytes, ParseForm reads
+	// unless overridden with WithMaxFormSize. It matches the limit net/http
+	// applies to url-encoded bodies.
+	DefaultMaxFormSize = 10 << 20
+)
+
+// defaultDecoder backs the package-level Parse, ParseRequest and ParseURL functions.
+var defaultDecoder = NewDecoder()
+
+// Decoder decodes query parameters into structs using its own configuration
+// and its own struct metadata cache.
+//
+// A Decoder must be created with NewDecoder. It is safe for concurrent use,
+// options are applied once at construction and never change afterwards.
+type Decoder struct {
+	tagName     string
+	separator   byte
+	timeLayouts []string
+	timeFormat  string // layout used by Encoder, see WithTimeFormat
+	allErrors   bool
+	notation    Notation
+	maxIndex    int
+	strict      bool
+	ignored     []string                      // glob patterns of keys strict mode tolerates
+	noGenerated bool                          // never call generated DecodeQuery methods
+	maxFormSize int64                         // body limit of ParseForm
+	converters  map[reflect.Type]scalarSetter // see WithConverter
+	dupPolicy   DuplicatePolicy               // see WithDuplicatePolicy
+
+	cache          sync.Map // structKey -> *structInfo, shared with Encoder
+	remainCache    sync.Map // reflect.Type -> bool, see treeHasRemain
+	converterCache sync.Map // reflect.Type -> bool, see treeUsesConverter
+}
+
+// Option configures a Decoder.
+type Option func(*Decoder)
+
+// NewDecoder returns a Decoder configured with the given options.
+//
+// Example:
+//
+//	d := qparser.NewDecoder(
+//		qparser.WithTagName("query"),
+//		qparser.WithSeparator('|'),
+//	)
+//	err := d.Parse(url.Values{"ids": {"1|2|3"}}, &f)
+func NewDecoder(opts ...Option) *Decoder {
+	d := &Decoder{
+		tagName:     DefaultTagName,
+		separator:   DefaultSeparator,
+		maxIndex:    DefaultMaxSliceIndex,
+		maxFormSize: DefaultMaxFormSize,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// WithTagName sets the struct tag key the Decoder reads field keys from.
+// An empty name keeps the default "qp".
+func WithTagName(name string) Option {
+	return func(d *Decoder) {
+		if name != "" {
+			d.tagName = name
+		}
+	}
+}
+
+// WithSeparator sets the byte used to split a single query value into
+// multiple slice elements. The default is ','. Like the "sep=" tag option,
+// it must be a printable byte other than a backslash, which escapes
+// separators: it panics otherwise.
+func WithSeparator(sep byte) Option {
+	if !validSeparator(sep) {
+		panic(fmt.Sprintf("qparser: invalid separator %q, want a printable byte other than a backslash", sep))
+	}
+	return func(d *Decoder) {
+		d.separator = sep
+	}
+}
+
+// WithTimeLayouts replaces the built-in timestamp detection with the given
+// layouts. Layouts are tried in order and the first successful parse wins.
+// Calling it without layouts keeps the built-in detection.
+func WithTimeLayouts(layouts ...string) Option {
+	return func(d *Decoder) {
+		d.timeLayouts = append([]string(nil), layouts...)
+	}
+}
+
+// WithNotation sets how compound keys, such as map entries, are written.
+// The default is BracketNotation (filter[status]), DotNotation reads and
+// writes filter.status instead.
+func WithNotation(n Notation) Option {
+	return func(d *Decoder) {
+		d.notation = n
+	}
+}
+
+// WithMaxSliceIndex sets the highest element index accepted when decoding
+// slices of structs from indexed keys such as items[3][name]. A larger index
+// fails with ErrOutOfRange. The default is DefaultMaxSliceIndex.
+func WithMaxSliceIndex(n int) Option {
+	return func(d *Decoder) {
+		d.maxIndex = n
+	}
+}
+
+// WithStrict makes the Decoder reject query keys that no field consumes,
+// including those of nested, prefixed and indexed structs and map entries.
+// They are reported together in an *UnknownParameterError wrapping
+// ErrUnknownParameter. Use WithIgnoredParams to tolerate some of them.
+func WithStrict() Option {
+	return func(d *Decoder) {
+		d.strict = true
+	}
+}
+
+// WithIgnoredParams lists glob patterns, in path.Match syntax, of keys that
+// strict mode tolerates without a matching field, e.g. "utm_*" for tracking
+// parameters. It panics if a pattern is malformed.
+func WithIgnoredParams(patterns ...string) Option {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic(fmt.Sprintf("qparser: invalid ignored parameter pattern %q: %v", pattern, err))
+		}
+	}
+	return func(d *Decoder) {
+		d.ignored = append(d.ignored, patterns...)
+	}
+}
+
+// WithoutGenerated makes the Decoder ignore DecodeQuery methods generated by
+// cmd/qparsergen and always decode through reflection, e.g. to compare both.
+func WithoutGenerated() Option {
+	return func(d *Decoder) {
+		d.noGenerated = true
+	}
+}
+
+// WithMaxFormSize sets the largest request body, in bytes, ParseForm reads.
+// Larger bodies fail with an *http.MaxBytesError. The default is
+// DefaultMaxFormSize.
+func WithMaxFormSize(n int64) Option {
+	return func(d *Decoder) {
+		d.maxFormSize = n
+	}
+}
+
+// WithDuplicatePolicy sets how fields decoded from a single value handle a
+// key sent several times. The "dup=" tag option overrides it per field, e.g.
+// `qp:"role,dup=error"`. The default is DuplicateFirst.
+func WithDuplicatePolicy(p DuplicatePolicy) Option {
+	return func(d *Decoder) {
+		d.dupPolicy = p
+	}
+}
+
+// WithAllErrors makes the Decoder continue past field failures and report
+// every one of them as FieldErrors, instead of stopping at the first.
+func WithAllErrors() Option {
+	return func(d *Decoder) {
+		d.allErrors = true
+	}
+}
+
+// Parse decodes the provided url.Values into the struct pointed to by dst.
+//
+// dst must be a pointer to a struct.
+func (d *Decoder) Parse(values url.Values, dst any) error {
+	return d.ParseSource(ValuesSource(values), dst)
+}
+
+// ParseSource decodes the values src holds into the struct pointed to by dst.
+// Every other Parse method is a shortcut to it for a given Source.
+//
+// dst must be a pointer to a struct. src should be a KeySource when dst has
+// map, struct slice or remain fields, and must be one in strict mode.
+func (d *Decoder) ParseSource(src Source, dst any) error {
+	if values, ok := src.(ValuesSource); ok {
+		if qd, ok := d.generatedFor(dst); ok {
+			return qd.DecodeQuery(url.Values(values))
+		}
+	}
+	return d.parse(keySource(src), dst, d.allErrors)
+}
+
+// ParseQueryString decodes the raw, still escaped, query string of a URL
+// (without the leading '?') into the struct pointed to by dst.
+//
+// The query is read in place rather than through url.Values: keys and values
+// are only unescaped, and copied, when they contain escapes. Queries that
+// url.ParseQuery rejects, e.g. ones using ';' as a separator, are rejected with
+// the same error before anything is decoded.
+func (d *Decoder) ParseQueryString(raw string, dst any) error {
+	if qd, ok := d.generatedFor(dst); ok {
+		values, err := url.ParseQuery(raw)
+		if err != nil {
+			return err
+		}
+		return qd.DecodeQuery(values)
+	}
+
+	q, err := parseRawQuery(raw)
+	if err != nil {
+		return err
+	}
+	defer releaseQuery(q)
+	return d.parse(q, dst, d.allErrors)
+}
+
+// generatedFor returns the generated decoder of dst when it implements
+// QueryDecoder and the Decoder's configuration is the one generated code
+// follows: default tag name, separator, time layouts and notation, stopping at
+// the first error, no strict mode, no converter for the types of dst and the
+// first of duplicate values. A nil dst is left to the reflective path, which
+// rejects it.
+func (d *Decoder) generatedFor(dst any) (QueryDecoder, bool) {
+	qd, ok := dst.(QueryDecoder)
+	rv := reflect.ValueOf(dst)
+	if !ok || rv.Kind() != reflect.Ptr || rv.IsNil() || d.allErrors || d.noGenerated || d.strict ||
+		d.tagName != DefaultTagName || d.separator != DefaultSeparator ||
+		len(d.timeLayouts) > 0 || d.notation != BracketNotation || d.dupPolicy != DuplicateFirst {
+		return nil, false
+	}
+	if rt := rv.Type().Elem(); rt.Kind() == reflect.Struct && d.treeUsesConverter(rt) {
+		return nil, false
+	}
+	return qd, true
+}
+
+// parse decodes query into dst, collecting every field failure into
+// FieldErrors when collect is set.
+func (d *Decoder) parse(query KeySource, dst any, collect bool) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("dst must be a pointer to struct")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	if _, ok := query.(lookupOnly); ok && d.strict {
+		return errors.New("strict mode requires a KeySource")
+	}
+
+	st := decodeState{query: query, collect: collect}
+	switch src := query.(type) {
+	case *formSource:
+		st.files = src.files
+	case *requestSource:
+		st.query, st.bound, st.binding = src.KeySource, src.bound, true
+	}
+	if d.strict || d.treeHasRemain(rt) {
+		st.consumed = make(map[string]struct{})
+	}
+	if err := d.parseStruct(&st, rv, rt, "", "", rt.Name()); err != nil {
+		return err
+	}
+	st.fillRemain()
+
+	var unknown error
+	if d.strict {
+		unknown = d.unknownParams(&st)
+	}
+	switch {
+	case len(st.errs) > 0 && unknown != nil:
+		return errors.Join(st.errs, unknown)
+	case len(st.errs) > 0:
+		return st.errs
+	default:
+		return unknown
+	}
+}
+
+// unknownParams reports the query keys no field consumed and no ignored
+// pattern matches, nil when there are none
+func (d *Decoder) unknownParams(st *decodeState) error {
+	// Loop bodies over Keys are closures, see parseStructSliceField
+	consumed := st.consumed
+	var keys []string
+	for key := range st.query.Keys() {
+		if _, ok := consumed[key]; ok || d.isIgnored(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	for key := range st.files {
+		if _, ok := consumed[key]; ok || d.isIgnored(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	slices.Sort(keys)
+	return &UnknownParameterError{Keys: slices.Compact(keys)}
+}
+
+// isIgnored reports whether key matches one of the ignored patterns
+func (d *Decoder) isIgnored(key string) bool {
+	for _, pattern := range d.ignored {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRequest decodes the query parameters of an http.Request into the
+// struct pointed to by dst, reading r.URL.RawQuery like ParseQueryString.
+func (d *Decoder) ParseRequest(r *http.Request, dst any) error {
+	return d.ParseQueryString(r.URL.RawQuery, dst)
+}
+
+// ParseForm decodes the body of a url-encoded or multipart form request into
+// the struct pointed to by dst, reading r.PostForm. The body is parsed when
+// r.PostForm is not set yet, up to the limit set with WithMaxFormSize.
+//
+// Fields of type *multipart.FileHeader and []*multipart.FileHeader receive the
+// files uploaded under their key, the first one and all of them respectively.
+func (d *Decoder) ParseForm(r *http.Request, dst any) error {
+	if err := d.readForm(r); err != nil {
+		return err
+	}
+	if qd, ok := d.generatedFor(dst); ok {
+		return qd.DecodeQuery(r.PostForm)
+	}
+
+	src := &formSource{ValuesSource: ValuesSource(r.PostForm)}
+	if r.MultipartForm != nil {
+		src.files = r.MultipartForm.File
+	}
+	return d.parse(src, dst, d.allErrors)
+}
+
+// Bind decodes the query parameters, path values, headers and cookies of r
+// into the struct pointed to by dst. Fields are bound to the query with the
+// Decoder's tag, and to the other parts of the request with the path, header
+// and cookie tags, e.g. `header:"X-Tenant,required"`. Failures report the part
+// of the request they concern in FieldError.Source.
+//
+// Bound fields take the same options and conversions as query fields. Their
+// keys are not prefixed by nested structs, and strict mode only considers
+// the query. Generated DecodeQuery methods are not used.
+func (d *Decoder) Bind(r *http.Request, dst any) error {
+	query, err := parseRawQuery(r.URL.RawQuery)
+	if err != nil {
+		return err
+	}
+	defer releaseQuery(query)
+	return d.parse(newRequestSource(r, query), dst, d.allErrors)
+}
+
+// ParseURL parses the query parameters from the provided URL string and
+// decodes them into the struct pointed to by dst.
+func (d *Decoder) ParseURL(addr string, dst any) error {
+	urlObj, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	return d.ParseQueryString(urlObj.RawQuery, dst)
+}
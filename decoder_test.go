@@ -0,0 +1,286 @@
+package qparser
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderTagName(t *testing.T) {
+	type params struct {
+		Page  int    `query:"page" qp:"p"`
+		Query string `query:"q"`
+	}
+
+	values := url.Values{"page": {"2"}, "p": {"9"}, "q": {"lorem"}}
+
+	var got params
+	err := NewDecoder(WithTagName("query")).Parse(values, &got)
+	require.NoError(t, err)
+	assert.Equal(t, params{Page: 2, Query: "lorem"}, got)
+
+	// The default decoder keeps reading the qp tag.
+	got = params{}
+	err = Parse(values, &got)
+	require.NoError(t, err)
+	assert.Equal(t, params{Page: 9}, got)
+}
+
+func TestDecoderSeparator(t *testing.T) {
+	type params struct {
+		IDs  []int     `qp:"ids"`
+		Tags *[]string `qp:"tags"`
+	}
+
+	d := NewDecoder(WithSeparator('|'))
+
+	var got params
+	err := d.Parse(url.Values{"ids": {"1|2", "3"}, "tags": {"a,b|c"}}, &got)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, got.IDs)
+	assert.Equal(t, &[]string{"a,b", "c"}, got.Tags)
+
+	t.Run("Invalid", func(t *testing.T) {
+		var got params
+		err := d.Parse(url.Values{"ids": {"1,2"}}, &got)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+	})
+
+	t.Run("Invalid-Separator", func(t *testing.T) {
+		for _, sep := range []byte{'\\', ' ', '\t', 0} {
+			assert.Panics(t, func() { WithSeparator(sep) }, "%q", sep)
+		}
+	})
+}
+
+func TestDecoderTimeLayouts(t *testing.T) {
+	type params struct {
+		From time.Time  `qp:"from"`
+		To   *time.Time `qp:"to"`
+	}
+
+	d := NewDecoder(WithTimeLayouts("02/01/2006", time.Kitchen))
+
+	var got params
+	err := d.Parse(url.Values{"from": {"04/07/2025"}, "to": {"3:04PM"}}, &got)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC).Equal(got.From))
+	assert.True(t, time.Date(0, 1, 1, 15, 4, 0, 0, time.UTC).Equal(*got.To))
+
+	t.Run("Builtin-Formats-Disabled", func(t *testing.T) {
+		var got params
+		err := d.Parse(url.Values{"from": {"2025-07-04"}}, &got)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+	})
+}
+
+func TestDecoderOwnCache(t *testing.T) {
+	type params struct {
+		A string `qp:"a" alt:"b"`
+	}
+
+	d1 := NewDecoder()
+	d2 := NewDecoder(WithTagName("alt"))
+
+	var p1, p2 params
+	values := url.Values{"a": {"from-a"}, "b": {"from-b"}}
+	require.NoError(t, d1.Parse(values, &p1))
+	require.NoError(t, d2.Parse(values, &p2))
+
+	assert.Equal(t, "from-a", p1.A)
+	assert.Equal(t, "from-b", p2.A)
+
+	ck := structKey{typ: reflect.TypeOf(params{})}
+	_, ok := defaultDecoder.cache.Load(ck)
+	assert.False(t, ok, "custom decoders must not populate the default cache")
+}
+
+func TestDecoderParseRequestAndURL(t *testing.T) {
+	type params struct {
+		IDs []int `qp:"ids"`
+	}
+
+	d := NewDecoder(WithSeparator(';'))
+
+	t.Run("Request", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com?ids=1%3B2%3B3", nil)
+		require.NoError(t, err)
+
+		var got params
+		require.NoError(t, d.ParseRequest(req, &got))
+		assert.Equal(t, []int{1, 2, 3}, got.IDs)
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		var got params
+		require.NoError(t, d.ParseURL("http://example.com?ids=4%3B5", &got))
+		assert.Equal(t, []int{4, 5}, got.IDs)
+
+		err := d.ParseURL("ht@tp://example.com", &got)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid-Destination", func(t *testing.T) {
+		var got params
+		assert.Error(t, d.Parse(url.Values{}, got))
+	})
+}
+
+func TestDecoderStrict(t *testing.T) {
+	type address struct {
+		City string `qp:"city"`
+	}
+
+	type item struct {
+		Name string `qp:"name"`
+	}
+
+	type params struct {
+		Page   int               `qp:"page"`
+		Limit  int               `qp:"limit,default=10"`
+		Addr   address           `qp:"addr"`
+		Items  []item            `qp:"items"`
+		Filter map[string]string `qp:"filter"`
+	}
+
+	d := NewDecoder(WithStrict())
+
+	t.Run("Known", func(t *testing.T) {
+		values := url.Values{
+			"page":           {"2"},
+			"addr[city]":     {"Paris"},
+			"items[0][name]": {"a"},
+			"filter[status]": {"active"},
+		}
+
+		var got params
+		require.NoError(t, d.Parse(values, &got))
+		assert.Equal(t, 2, got.Page)
+		assert.Equal(t, 10, got.Limit)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		values := url.Values{
+			"page":           {"2"},
+			"pgae":           {"3"},
+			"addr[zip]":      {"75001"},
+			"items[0][name]": {"a"},
+			"items[1][qty]":  {"1"},
+		}
+
+		var got params
+		err := d.Parse(values, &got)
+		require.ErrorIs(t, err, ErrUnknownParameter)
+
+		var unknownErr *UnknownParameterError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, []string{"addr[zip]", "items[1][qty]", "pgae"}, unknownErr.Keys)
+
+		// The known keys are still decoded
+		assert.Equal(t, 2, got.Page)
+		assert.Equal(t, "a", got.Items[0].Name)
+	})
+
+	t.Run("Ignored-Params", func(t *testing.T) {
+		d := NewDecoder(WithStrict(), WithIgnoredParams("utm_*", "fbclid"))
+
+		var got params
+		err := d.Parse(url.Values{"page": {"1"}, "utm_source": {"mail"}, "fbclid": {"x"}}, &got)
+		require.NoError(t, err)
+
+		err = d.Parse(url.Values{"utm": {"mail"}}, &got)
+		assert.ErrorIs(t, err, ErrUnknownParameter)
+
+		assert.Panics(t, func() { WithIgnoredParams("[") })
+	})
+
+	t.Run("Field-Error-First", func(t *testing.T) {
+		var got params
+		err := d.Parse(url.Values{"page": {"x"}, "pgae": {"3"}}, &got)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.NotErrorIs(t, err, ErrUnknownParameter)
+	})
+
+	t.Run("All-Errors", func(t *testing.T) {
+		d := NewDecoder(WithStrict(), WithAllErrors())
+
+		var got params
+		err := d.Parse(url.Values{"page": {"x"}, "pgae": {"3"}}, &got)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.ErrorIs(t, err, ErrUnknownParameter)
+
+		var fieldErrs FieldErrors
+		require.ErrorAs(t, err, &fieldErrs)
+		assert.Len(t, fieldErrs, 1)
+	})
+
+	t.Run("Not-Strict", func(t *testing.T) {
+		var got params
+		assert.NoError(t, Parse(url.Values{"pgae": {"3"}}, &got))
+	})
+}
+
+// handDecoded mimics a qparsergen output, recording whether it was used
+type handDecoded struct {
+	Page    int `qp:"page"`
+	decoded bool
+}
+
+func (h *handDecoded) DecodeQuery(values url.Values) error {
+	h.decoded = true
+	h.Page = len(values["page"])
+	return nil
+}
+
+func TestDecoderGenerated(t *testing.T) {
+	values := url.Values{"page": {"7"}}
+
+	tests := []struct {
+		name      string
+		parse     func(dst *handDecoded) error
+		generated bool
+	}{
+		{"Parse", func(dst *handDecoded) error { return Parse(values, dst) }, true},
+		{"ParseQueryString", func(dst *handDecoded) error { return ParseQueryString("page=7", dst) }, true},
+		{"ParseAs", func(dst *handDecoded) error {
+			var err error
+			*dst, err = ParseAs[handDecoded](values)
+			return err
+		}, true},
+		{"Without-Generated", func(dst *handDecoded) error {
+			return NewDecoder(WithoutGenerated()).Parse(values, dst)
+		}, false},
+		{"ParseAll", func(dst *handDecoded) error { return ParseAll(values, dst) }, false},
+		{"All-Errors", func(dst *handDecoded) error { return NewDecoder(WithAllErrors()).Parse(values, dst) }, false},
+		{"Strict", func(dst *handDecoded) error { return NewDecoder(WithStrict()).Parse(values, dst) }, false},
+		{"Tag-Name", func(dst *handDecoded) error { return NewDecoder(WithTagName("q")).Parse(values, dst) }, false},
+		{"Converter", func(dst *handDecoded) error {
+			return NewDecoder(WithConverter(func(s string) (int, error) { return strconv.Atoi(s) })).Parse(values, dst)
+		}, false},
+		{"Unused-Converter", func(dst *handDecoded) error {
+			return NewDecoder(WithConverter(func(s string) (string, error) { return s, nil })).Parse(values, dst)
+		}, true},
+		{"Duplicate-Policy", func(dst *handDecoded) error {
+			return NewDecoder(WithDuplicatePolicy(DuplicateLast)).Parse(values, dst)
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got handDecoded
+			require.NoError(t, tt.parse(&got))
+			assert.Equal(t, tt.generated, got.decoded)
+			if tt.generated {
+				assert.Equal(t, 1, got.Page)
+			} else if tt.name != "Tag-Name" {
+				assert.Equal(t, 7, got.Page)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package qparser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DuplicatePolicy decides how a field decoded from a single value, such as
+// an int or a string, handles a key sent several times, e.g. ?page=1&page=9.
+// Slices and Unmarshaler types receive every value and are not concerned.
+type DuplicatePolicy uint8
+
+const (
+	// DuplicateFirst decodes the first value and ignores the others. It is
+	// the default.
+	DuplicateFirst DuplicatePolicy = iota
+
+	// DuplicateLast decodes the last value and ignores the others.
+	DuplicateLast
+
+	// DuplicateError fails with ErrDuplicateValue, for parameters such as a
+	// role or a tenant that must not be open to parameter pollution.
+	DuplicateError
+
+	// DuplicateJoin joins the values with the Decoder's separator and
+	// decodes the result, e.g. "a,b" into a string field.
+	DuplicateJoin
+)
+
+// String returns the name of the policy, as written in the "dup=" tag option.
+func (p DuplicatePolicy) String() string {
+	switch p {
+	case DuplicateLast:
+		return "last"
+	case DuplicateError:
+		return "error"
+	case DuplicateJoin:
+		return "join"
+	}
+	return "first"
+}
+
+// parseDuplicatePolicy returns the policy named s, see DuplicatePolicy.String
+func parseDuplicatePolicy(s string) (DuplicatePolicy, bool) {
+	for p := DuplicateFirst; p <= DuplicateJoin; p++ {
+		if p.String() == s {
+			return p, true
+		}
+	}
+	return DuplicateFirst, false
+}
+
+// resolveDuplicates sets the duplicate policy of field: the "dup=" option of
+// its tag if any, which only scalar fields accept, or the Decoder's.
+func (d *Decoder) resolveDuplicates(field *fieldInfo, opts tagOptions) error {
+	field.dup = d.dupPolicy
+	if !opts.hasDup {
+		return nil
+	}
+	if !takesOneValue(field) {
+		return fmt.Errorf("%w: option \"dup\" requires a field decoded from a single value, got %v", ErrInvalidTag, field.typ)
+	}
+	field.dup = opts.dup
+	return nil
+}
+
+// takesOneValue reports whether field decodes a single value of its key
+func takesOneValue(field *fieldInfo) bool {
+	switch field.unmarshal {
+	case unmarshalText:
+		return true
+	case unmarshalQuery:
+		return false
+	}
+	typ := field.typ
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Map:
+		return false
+	}
+	return field.mapValue == nil && field.structElem == nil && !field.remain && !field.file
+}
+
+// withDuplicates wraps the setter of a field taking one value so the values
+// of a repeated key are reduced to one according to policy
+func withDuplicates(set setter, policy DuplicatePolicy, sep byte) setter {
+	return func(fv reflect.Value, vals []string) error {
+		if len(vals) > 1 {
+			switch policy {
+			case DuplicateLast:
+				vals = vals[len(vals)-1:]
+			case DuplicateError:
+				return fmt.Errorf("%w: %d values sent", ErrDuplicateValue, len(vals))
+			case DuplicateJoin:
+				vals = []string{strings.Join(vals, string(sep))}
+			}
+		}
+		return set(fv, vals)
+	}
+}
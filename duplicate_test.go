@@ -0,0 +1,147 @@
+package qparser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantQuery struct {
+	Tenant string            `qp:"tenant,dup=error"`
+	Role   *string           `qp:"role"`
+	Page   int               `qp:"page"`
+	Since  time.Time         `qp:"since"`
+	Tags   []string          `qp:"tags"`
+	Labels map[string]string `qp:"labels"`
+	Owner  string            `qp:"owner,dup=first"`
+}
+
+func TestDuplicatePolicy(t *testing.T) {
+	values := url.Values{
+		"role":          {"viewer", "admin"},
+		"page":          {"1", "9"},
+		"since":         {"2024-01-02", "2024-03-04"},
+		"tags":          {"a", "b"},
+		"labels[color]": {"red", "blue"},
+		"owner":         {"ann", "bob"},
+	}
+	role := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		policy DuplicatePolicy
+		want   tenantQuery
+	}{
+		{"First", DuplicateFirst, tenantQuery{
+			Role:   role("viewer"),
+			Page:   1,
+			Since:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			Tags:   []string{"a", "b"},
+			Labels: map[string]string{"color": "red"},
+			Owner:  "ann",
+		}},
+		{"Last", DuplicateLast, tenantQuery{
+			Role:   role("admin"),
+			Page:   9,
+			Since:  time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+			Tags:   []string{"a", "b"},
+			Labels: map[string]string{"color": "blue"},
+			Owner:  "ann",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got tenantQuery
+			require.NoError(t, NewDecoder(WithDuplicatePolicy(tt.policy)).Parse(values, &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Join", func(t *testing.T) {
+		joined := url.Values{"role": values["role"], "tags": values["tags"], "labels[color]": values["labels[color]"], "owner": values["owner"]}
+		var got tenantQuery
+		require.NoError(t, NewDecoder(WithDuplicatePolicy(DuplicateJoin)).Parse(joined, &got))
+		assert.Equal(t, "viewer,admin", *got.Role)
+		assert.Equal(t, map[string]string{"color": "red,blue"}, got.Labels)
+		assert.Equal(t, []string{"a", "b"}, got.Tags)
+		assert.Equal(t, "ann", got.Owner)
+
+		// The joined value is decoded like any other, and may not be valid
+		var page tenantQuery
+		err := NewDecoder(WithDuplicatePolicy(DuplicateJoin)).Parse(url.Values{"page": {"1", "9"}}, &page)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			values url.Values
+			field  string
+		}{
+			{"Tag", url.Values{"tenant": {"acme", "evil"}}, "Tenant"},
+			{"Decoder", url.Values{"page": {"1", "1"}}, "Page"},
+			{"Map-Value", url.Values{"labels[color]": {"red", "blue"}}, "Labels[color]"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got tenantQuery
+				err := NewDecoder(WithDuplicatePolicy(DuplicateError)).Parse(tt.values, &got)
+				assert.ErrorIs(t, err, ErrDuplicateValue)
+
+				var fieldErr *FieldError
+				require.ErrorAs(t, err, &fieldErr)
+				assert.Equal(t, "tenantQuery."+tt.field, fieldErr.FieldName)
+				assert.EqualError(t, fieldErr.Err, "duplicate value: 2 values sent")
+			})
+		}
+
+		// A single value passes, and so do repeated keys of slices
+		var got tenantQuery
+		require.NoError(t, Parse(url.Values{"tenant": {"acme"}, "tags": {"a", "b"}}, &got))
+		assert.Equal(t, "acme", got.Tenant)
+	})
+
+	t.Run("Bound-Field", func(t *testing.T) {
+		type params struct {
+			Tenant string `header:"X-Tenant,dup=error"`
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Add("X-Tenant", "acme")
+		req.Header.Add("X-Tenant", "evil")
+
+		var got params
+		assert.ErrorIs(t, Bind(req, &got), ErrDuplicateValue)
+	})
+
+	t.Run("Invalid-Tags", func(t *testing.T) {
+		tests := []struct {
+			name string
+			dst  any
+		}{
+			{"Unknown-Policy", &struct {
+				S string `qp:"s,dup=random"`
+			}{}},
+			{"Missing-Policy", &struct {
+				S string `qp:"s,dup"`
+			}{}},
+			{"Slice", &struct {
+				S []string `qp:"s,dup=error"`
+			}{}},
+			{"Struct-Slice", &struct {
+				Items []struct {
+					A int `qp:"a"`
+				} `qp:"items,dup=last"`
+			}{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.ErrorIs(t, Parse(url.Values{}, tt.dst), ErrInvalidTag)
+			})
+		}
+	})
+}
@@ -0,0 +1,394 @@
+package qparser
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshaler is the encoding counterpart of Unmarshaler. MarshalQuery returns
+// every value to send for the field's key. Returning no values omits the key.
+//
+// Marshaler takes precedence over encoding.TextMarshaler, which is also supported.
+type Marshaler interface {
+	MarshalQuery() ([]string, error)
+}
+
+// marshalKind records which encoding interface a type implements, resolved
+// once when struct metadata is built.
+type marshalKind uint8
+
+const (
+	marshalNone  marshalKind = iota
+	marshalQuery             // implements Marshaler
+	marshalText              // implements encoding.TextMarshaler
+)
+
+var (
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// defaultEncoder backs the package-level Encode and EncodeToString functions.
+// It shares its configuration and metadata cache with the default Decoder.
+var defaultEncoder = &Encoder{dec: defaultDecoder}
+
+// Encoder turns qp-tagged structs back into url.Values, following the same
+// tags and options a Decoder configured with the same options reads.
+//
+// For supported types, decoding the output of an Encoder with a Decoder built
+// from the same options yields the original struct.
+type Encoder struct {
+	dec *Decoder // source of configuration and struct metadata
+}
+
+// NewEncoder returns an Encoder configured with the given options.
+// Decoding-only options are accepted and ignored.
+func NewEncoder(opts ...Option) *Encoder {
+	return &Encoder{dec: NewDecoder(opts...)}
+}
+
+// WithTimeFormat sets the layout an Encoder uses to format time.Time fields.
+// By default the first layout given to WithTimeLayouts is used, or
+// time.RFC3339Nano when none is configured, so encoded times decode back.
+func WithTimeFormat(layout string) Option {
+	return func(d *Decoder) {
+		d.timeFormat = layout
+	}
+}
+
+// Encode encodes the struct, or pointer to struct, src into url.Values using the
+// default Encoder.
+//
+// Nil pointers are omitted, slices are encoded as repeated keys (or a single
+// separator-joined value with the "join" tag option) and fields tagged
+// "omitempty" are omitted when they hold their zero value.
+//
+// Example:
+//
+//	values, err := qparser.Encode(Pagination{Page: 2, Limit: 20})
+//	// values: url.Values{"page": {"2"}, "limit": {"20"}}
+func Encode(src any) (url.Values, error) {
+	return defaultEncoder.Encode(src)
+}
+
+// EncodeToString encodes src like Encode and returns the URL-encoded query
+// string, sorted by key.
+func EncodeToString(src any) (string, error) {
+	return defaultEncoder.EncodeToString(src)
+}
+
+// Encode encodes the struct, or pointer to struct, src into url.Values.
+func (e *Encoder) Encode(src any) (url.Values, error) {
+	rv := reflect.ValueOf(src)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("src must be a non-nil struct or pointer to struct")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("src must be a non-nil struct or pointer to struct")
+	}
+
+	values := make(url.Values)
+	rt := rv.Type()
+	if err := e.encodeStruct(values, rv, rt, "", "", rt.Name()); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// EncodeToString encodes src and returns the URL-encoded query string, sorted by key.
+func (e *Encoder) EncodeToString(src any) (string, error) {
+	values, err := e.Encode(src)
+	if err != nil {
+		return "", err
+	}
+	return values.Encode(), nil
+}
+
+// encodeStruct appends the values of every tagged field of rv to values.
+// prefix, base and path follow the meaning they have in Decoder.parseStruct.
+func (e *Encoder) encodeStruct(values url.Values, rv reflect.Value, rt reflect.Type, prefix, base, path string) error {
+	info := e.dec.getStructCache(rt, prefix)
+	if info.hasUnexportedWithTag {
+		return ErrUnexportedStruct
+	}
+	if info.err != nil {
+		return info.err
+	}
+
+	for _, field := range info.fields {
+		fieldPath := joinPath(path, field.name)
+		fv := rv.FieldByIndex(field.index)
+
+		if field.isNested {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if err := e.encodeStruct(values, fv, fv.Type(), field.key, base, fieldPath); err != nil {
+				if _, ok := err.(*FieldError); ok {
+					return err
+				}
+				return wrapFieldError(fieldPath, err)
+			}
+			continue
+		}
+
+		if field.remain {
+			// Written back as-is, alongside the keys of the other fields
+			iter := fv.MapRange()
+			for iter.Next() {
+				key := iter.Key().String()
+				values[key] = append(values[key], iter.Value().Interface().([]string)...)
+			}
+			continue
+		}
+
+		if field.file || field.origin != originQuery {
+			continue // uploads and bound fields have no query representation
+		}
+
+		if field.omitempty && fv.IsZero() {
+			continue
+		}
+
+		key := e.dec.fullKey(base, field.key)
+		if field.mapValue != nil {
+			if err := e.encodeMap(values, fv, &field, key, fieldPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.structElem != nil {
+			if err := e.encodeStructSlice(values, fv, &field, key, fieldPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		vals, err := e.encodeField(fv, &field)
+		if err != nil {
+			return wrapFieldError(fieldPath, err)
+		}
+		if len(vals) > 0 {
+			values[key] = append(values[key], vals...)
+		}
+	}
+	return nil
+}
+
+// encodeMap appends one key per map entry, composed with the configured
+// Notation, in sorted order of the map keys
+func (e *Encoder) encodeMap(values url.Values, fv reflect.Value, field *fieldInfo, key, path string) error {
+	keys := fv.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	for _, k := range keys {
+		sub := k.String()
+		vals, err := e.encodeField(fv.MapIndex(k), field.mapValue)
+		if err != nil {
+			return wrapFieldError(path+"["+sub+"]", err)
+		}
+		if len(vals) > 0 {
+			entryKey := e.dec.subKey(key, sub)
+			values[entryKey] = append(values[entryKey], vals...)
+		}
+	}
+	return nil
+}
+
+// encodeStructSlice encodes every element of a []S or []*S field under its
+// indexed key, e.g. items[0][name]. Nil elements are skipped.
+func (e *Encoder) encodeStructSlice(values url.Values, fv reflect.Value, field *fieldInfo, key, path string) error {
+	for i := 0; i < fv.Len(); i++ {
+		elem := fv.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+
+		n := strconv.Itoa(i)
+		elemPath := path + "[" + n + "]"
+		if err := e.encodeStruct(values, elem, field.structElem, "", key+"["+n+"]", elemPath); err != nil {
+			if _, ok := err.(*FieldError); ok {
+				return err
+			}
+			return wrapFieldError(elemPath, err)
+		}
+	}
+	return nil
+}
+
+// encodeField returns the query values of a single field
+func (e *Encoder) encodeField(fv reflect.Value, field *fieldInfo) ([]string, error) {
+	if field.marshal != marshalNone {
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				return nil, nil
+			}
+			fv = fv.Elem()
+		}
+		return marshal(field.marshal, fv)
+	}
+
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return nil, nil
+		}
+		fv = fv.Elem()
+	}
+	if fv.Kind() != reflect.Slice {
+		return e.formatValue(fv, marshalNone, field.enum)
+	}
+
+	vals := make([]string, 0, fv.Len())
+	for i := 0; i < fv.Len(); i++ {
+		elem := fv.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		s, err := e.formatValue(elem, field.elemMarshal, field.enum)
+		if err != nil {
+			return nil, fmt.Errorf("element [%d]: %w", i, err)
+		}
+		if field.nosplit {
+			vals = append(vals, s...)
+			continue
+		}
+		// The decoder splits every value, separators are escaped to keep
+		// elements whole
+		for _, v := range s {
+			vals = append(vals, escapeElem(v, field.sep))
+		}
+	}
+
+	if field.join && len(vals) > 0 {
+		return []string{strings.Join(vals, string(field.sep))}, nil
+	}
+	return vals, nil
+}
+
+// formatValue formats a non-pointer, non-slice value. mk is the cached
+// encoding interface of its type, and enum the values it is restricted to.
+func (e *Encoder) formatValue(fv reflect.Value, mk marshalKind, enum *enumSet) ([]string, error) {
+	if mk != marshalNone {
+		return marshal(mk, fv)
+	}
+	if enum != nil && enum.indexed {
+		return enum.format(fv)
+	}
+
+	var s string
+	switch fv.Kind() {
+	case reflect.Struct:
+		if fv.Type() != timeType {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedKind, fv.Kind())
+		}
+		s = fv.Interface().(time.Time).Format(e.timeFormat())
+	case reflect.String:
+		s = fv.String()
+	case reflect.Bool:
+		s = strconv.FormatBool(fv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(fv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(fv.Uint(), 10)
+	case reflect.Float32:
+		s = strconv.FormatFloat(fv.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		s = strconv.FormatFloat(fv.Float(), 'g', -1, 64)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedKind, fv.Kind())
+	}
+	return []string{s}, nil
+}
+
+// timeFormat returns the layout used to format time.Time values
+func (e *Encoder) timeFormat() string {
+	switch {
+	case e.dec.timeFormat != "":
+		return e.dec.timeFormat
+	case len(e.dec.timeLayouts) > 0:
+		return e.dec.timeLayouts[0]
+	default:
+		return time.RFC3339Nano
+	}
+}
+
+// marshalKindOf reports the encoding interface implemented by t or *t.
+// time.Time is excluded so it is formatted with the configured layout.
+func marshalKindOf(t reflect.Type) marshalKind {
+	if t == timeType || t.Kind() == reflect.Interface {
+		return marshalNone
+	}
+	pt := reflect.PointerTo(t)
+	switch {
+	case t.Implements(marshalerType) || pt.Implements(marshalerType):
+		return marshalQuery
+	case t.Implements(textMarshalerType) || pt.Implements(textMarshalerType):
+		return marshalText
+	}
+	return marshalNone
+}
+
+// marshalKinds resolves the encoding interfaces of a field type and, when
+// the field does not implement one itself, of its slice element type.
+func marshalKinds(typ reflect.Type) (field, elem marshalKind) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if field = marshalKindOf(typ); field != marshalNone {
+		return field, marshalNone
+	}
+	if typ.Kind() == reflect.Slice {
+		elemType := typ.Elem()
+		if elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		elem = marshalKindOf(elemType)
+	}
+	return field, elem
+}
+
+// marshal encodes fv through the interface selected by kind. Pointer receiver
+// methods are reached through a copy when fv is not addressable.
+func marshal(kind marshalKind, fv reflect.Value) ([]string, error) {
+	var target any
+	if fv.CanAddr() {
+		target = fv.Addr().Interface()
+	} else {
+		ptr := reflect.New(fv.Type())
+		ptr.Elem().Set(fv)
+		target = ptr.Interface()
+	}
+
+	switch kind {
+	case marshalQuery:
+		return target.(Marshaler).MarshalQuery()
+	case marshalText:
+		text, err := target.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return []string{string(text)}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,215 @@
+package qparser
+
+import (
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// level is an enum encoded through Marshaler and decoded through Unmarshaler
+type level int
+
+func (l level) MarshalQuery() ([]string, error) {
+	return []string{strings.Repeat("*", int(l))}, nil
+}
+
+func (l *level) UnmarshalQuery(values []string) error {
+	*l = level(len(values[0]))
+	return nil
+}
+
+func TestEncode(t *testing.T) {
+	type child struct {
+		Limit int `qp:"limit"`
+	}
+
+	type params struct {
+		Page    int        `qp:"page"`
+		Query   string     `qp:"q,omitempty"`
+		Active  *bool      `qp:"active"`
+		IDs     []int      `qp:"ids"`
+		Tags    []string   `qp:"tags,join"`
+		Ratio   float32    `qp:"ratio"`
+		From    time.Time  `qp:"from"`
+		Until   *time.Time `qp:"until"`
+		Addr    netip.Addr `qp:"addr,omitempty"`
+		Level   level      `qp:"level"`
+		Skipped string     `qp:"-"`
+		C       child
+		CP      *child
+	}
+
+	from := time.Date(2025, 7, 1, 8, 30, 0, 0, time.UTC)
+	src := params{
+		Page:   2,
+		Active: ptr(false),
+		IDs:    []int{1, 2, 3},
+		Tags:   []string{"a", "b"},
+		Ratio:  0.1,
+		From:   from,
+		Addr:   netip.MustParseAddr("10.0.0.1"),
+		Level:  3,
+		C:      child{Limit: 20},
+	}
+
+	values, err := Encode(src)
+	require.NoError(t, err)
+
+	assert.Equal(t, url.Values{
+		"page":   {"2"},
+		"active": {"false"},
+		"ids":    {"1", "2", "3"},
+		"tags":   {"a,b"},
+		"ratio":  {"0.1"},
+		"from":   {"2025-07-01T08:30:00Z"},
+		"addr":   {"10.0.0.1"},
+		"level":  {"***"},
+		"limit":  {"20"},
+	}, values)
+
+	t.Run("Pointer-Source", func(t *testing.T) {
+		fromPtr, err := Encode(&src)
+		require.NoError(t, err)
+		assert.Equal(t, values, fromPtr)
+	})
+
+	t.Run("String", func(t *testing.T) {
+		s, err := EncodeToString(Pagination{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, "limit=10&page=1", s)
+	})
+
+	t.Run("Invalid-Source", func(t *testing.T) {
+		_, err := Encode(42)
+		assert.Error(t, err)
+
+		_, err = Encode((*params)(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("Remain", func(t *testing.T) {
+		type withRemain struct {
+			Page int        `qp:"page"`
+			Rest url.Values `qp:",remain"`
+		}
+		values, err := Encode(withRemain{Page: 1, Rest: url.Values{"facet": {"a", "b"}}})
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"page": {"1"}, "facet": {"a", "b"}}, values)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		type unsupported struct {
+			F1 complex64 `qp:"f1"`
+		}
+		_, err := Encode(unsupported{})
+		assert.ErrorIs(t, err, ErrUnsupportedKind)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "unsupported.F1", fieldErr.FieldName)
+	})
+
+	t.Run("Unexported", func(t *testing.T) {
+		type unexported struct {
+			f1 string `qp:"f1"` //nolint
+		}
+		_, err := Encode(unexported{})
+		assert.ErrorIs(t, err, ErrUnexportedStruct)
+	})
+}
+
+func TestEncoderOptions(t *testing.T) {
+	type params struct {
+		From time.Time `qp:"from"`
+		Tags []string  `qp:"tags,join"`
+	}
+
+	src := params{
+		From: time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC),
+		Tags: []string{"a", "b"},
+	}
+
+	t.Run("Time-Layouts", func(t *testing.T) {
+		opts := []Option{WithTimeLayouts("02/01/2006"), WithSeparator('|')}
+
+		values, err := NewEncoder(opts...).Encode(src)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"from": {"04/07/2025"}, "tags": {"a|b"}}, values)
+
+		var got params
+		require.NoError(t, NewDecoder(opts...).Parse(values, &got))
+		assert.Equal(t, src, got)
+	})
+
+	t.Run("Time-Format", func(t *testing.T) {
+		values, err := NewEncoder(WithTimeFormat(time.DateOnly)).Encode(src)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2025-07-04"}, values["from"])
+	})
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	type child struct {
+		F3 *string `qp:"f3"`
+		F4 []uint8 `qp:"f4,join"`
+	}
+
+	type emptyChild struct {
+		F18 *string `qp:"f18"`
+	}
+
+	type roundTrip struct {
+		F1  string        `qp:"f1"`
+		F2  *int64        `qp:"f2"`
+		F5  []float64     `qp:"f5"`
+		F6  *[]bool       `qp:"f6"`
+		F7  []*string     `qp:"f7"`
+		F8  time.Time     `qp:"f8"`
+		F9  *time.Time    `qp:"f9"`
+		F10 int8Alias     `qp:"f10"`
+		F11 *netip.Addr   `qp:"f11"`
+		F12 []netip.Addr  `qp:"f12"`
+		F13 uint64        `qp:"f13,omitempty"`
+		F14 float32Alias  `qp:"f14"`
+		F15 []status      `qp:"f15,omitempty"`
+		F16 *float64      `qp:"f16"`
+		F17 []*netip.Addr `qp:"f17"`
+		C   child
+		CP  *emptyChild
+	}
+
+	now := time.Now().Truncate(0)
+	src := roundTrip{
+		F1:  "hello, world",
+		F2:  ptr(int64(-42)),
+		F5:  []float64{1.5, 0.1, 1e-7},
+		F6:  &[]bool{true, false},
+		F7:  []*string{ptr("a"), ptr("b")},
+		F8:  now,
+		F9:  ptr(now.UTC()),
+		F10: -8,
+		F11: ptr(netip.MustParseAddr("::1")),
+		F12: []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")},
+		F14: 3.14,
+		F16: ptr(0.0),
+		F17: []*netip.Addr{ptr(netip.MustParseAddr("10.0.0.3"))},
+		C:   child{F3: ptr("nested"), F4: []uint8{1, 255}},
+		CP:  &emptyChild{},
+	}
+
+	values, err := Encode(src)
+	require.NoError(t, err)
+
+	var got roundTrip
+	require.NoError(t, Parse(values, &got))
+
+	assert.True(t, src.F8.Equal(got.F8))
+	assert.True(t, src.F9.Equal(*got.F9))
+	got.F8, got.F9 = src.F8, src.F9
+	assert.Equal(t, src, got)
+}
@@ -0,0 +1,223 @@
+package qparser
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Enum is implemented by types accepting a fixed set of values, such as a
+// sort direction or a status. Values lists them, the first one being the
+// index 0 for integer types, see the "enum=" tag option for how they are
+// decoded. Plain numeric fields restricted with "enum=" list numbers instead,
+// compared by value.
+type Enum interface {
+	Values() []string
+}
+
+var enumType = reflect.TypeFor[Enum]()
+
+// enumSet is the set of values a field accepts, from its "enum=" option or
+// the Enum implementation of its type
+type enumSet struct {
+	values []string
+	fold   bool // compare case-insensitively, see the "fold" option
+
+	// indexed marks an integer type decoding itself from the index of its
+	// value rather than through a converter or unmarshaler
+	indexed bool
+
+	// numbers holds the values decoded with the plain numeric type of the
+	// field, which compares them by value rather than by name
+	numbers []reflect.Value
+}
+
+// String returns the values of the set the way the tag option lists them
+func (e *enumSet) String() string {
+	return strings.Join(e.values, "|")
+}
+
+// index returns the position of val in the set, -1 if it is not allowed
+func (e *enumSet) index(val string) int {
+	if e.fold {
+		return slices.IndexFunc(e.values, func(v string) bool {
+			return strings.EqualFold(v, val)
+		})
+	}
+	return slices.Index(e.values, val)
+}
+
+// notAllowed is the error reported for a value outside the set
+func (e *enumSet) notAllowed(val string) error {
+	return fmt.Errorf("%w: %q, want one of %s", ErrNotAllowed, val, e)
+}
+
+// enumOf returns the set of values accepted by a field of type typ, given its
+// tag options, nil when the field is not an enum. The "enum=" option wins over
+// the Enum implementation of the scalar type, that of the field or of its
+// slice elements, through pointers.
+func (d *Decoder) enumOf(typ reflect.Type, opts tagOptions) (*enumSet, error) {
+	scalar := scalarType(typ)
+	var values []string
+	switch {
+	case opts.hasEnum:
+		values = strings.Split(opts.enumValues, "|")
+		if slices.Contains(values, "") {
+			return nil, fmt.Errorf("%w: option \"enum\" lists an empty value", ErrInvalidTag)
+		}
+	case implementsEnum(scalar):
+		values = reflect.New(scalar).Interface().(Enum).Values()
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%w: %v.Values returned no values", ErrInvalidTag, scalar)
+		}
+	case opts.fold:
+		return nil, fmt.Errorf("%w: option \"fold\" requires an enum", ErrInvalidTag)
+	default:
+		return nil, nil
+	}
+
+	set := &enumSet{values: values, fold: opts.fold}
+	if d.converter(scalar) != nil || unmarshalKindOf(scalar) != unmarshalNone {
+		return set, nil
+	}
+	// Only Enum types name their integers, other numbers are listed as such
+	switch scalar.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if implementsEnum(scalar) {
+			set.indexed = true
+			break
+		}
+		fallthrough
+	case reflect.Float32, reflect.Float64:
+		if err := d.decodeNumbers(set, scalar); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// decodeNumbers decodes the values of set with typ, a plain numeric type
+func (d *Decoder) decodeNumbers(set *enumSet, typ reflect.Type) error {
+	decode := d.compileScalar(typ, unmarshalNone, nil)
+	set.numbers = make([]reflect.Value, len(set.values))
+	for i, val := range set.values {
+		n := reflect.New(typ).Elem()
+		if err := decode(n, val); err != nil {
+			return fmt.Errorf("%w: enum value %q of %v: %w", ErrInvalidTag, val, typ, err)
+		}
+		set.numbers[i] = n
+	}
+	return nil
+}
+
+// hasNumber reports whether fv, decoded by a numeric set, equals one of its
+// values
+func (e *enumSet) hasNumber(fv reflect.Value) bool {
+	for _, n := range e.numbers {
+		if fv.Equal(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// scalarType returns the type a field of type typ decodes each value into:
+// typ itself, or its slice element, through pointers
+func scalarType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Slice && unmarshalKindOf(typ) == unmarshalNone {
+		typ = typ.Elem()
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+	}
+	return typ
+}
+
+// implementsEnum reports whether t or *t implements Enum
+func implementsEnum(t reflect.Type) bool {
+	return t.Kind() != reflect.Interface && reflect.PointerTo(t).Implements(enumType)
+}
+
+// compileEnum returns the scalarSetter of typ, a non-pointer type, for values
+// restricted to enum. Allowed values are decoded in their canonical form, as
+// listed by the set: integer Enum types take its index, other types are
+// decoded as usual. Plain numbers are decoded first and compared by value.
+func (d *Decoder) compileEnum(typ reflect.Type, um unmarshalKind, enum *enumSet) scalarSetter {
+	if enum.numbers != nil {
+		set := d.compileScalar(typ, um, nil)
+		return func(fv reflect.Value, val string) error {
+			if err := set(fv, val); err != nil || !enum.hasNumber(fv) {
+				return enum.notAllowed(val)
+			}
+			return nil
+		}
+	}
+
+	if enum.indexed {
+		unsigned := typ.Kind() >= reflect.Uint && typ.Kind() <= reflect.Uint64
+		return func(fv reflect.Value, val string) error {
+			i := enum.index(val)
+			if i < 0 {
+				return enum.notAllowed(val)
+			}
+			if unsigned {
+				fv.SetUint(uint64(i))
+			} else {
+				fv.SetInt(int64(i))
+			}
+			return nil
+		}
+	}
+
+	set := d.compileScalar(typ, um, nil)
+	return func(fv reflect.Value, val string) error {
+		i := enum.index(val)
+		if i < 0 {
+			return enum.notAllowed(val)
+		}
+		return set(fv, enum.values[i])
+	}
+}
+
+// format returns the value of an indexed enum, the inverse of compileEnum
+func (e *enumSet) format(fv reflect.Value) ([]string, error) {
+	var n int64
+	if fv.CanInt() {
+		n = fv.Int()
+	} else if u := fv.Uint(); u <= math.MaxInt64 {
+		n = int64(u)
+	} else {
+		n = -1
+	}
+	if n < 0 || n >= int64(len(e.values)) {
+		// Not formatted with %v, a String method may index the values too
+		return nil, fmt.Errorf("%w: %d is not an index of %s", ErrOutOfRange, n, e)
+	}
+	return []string{e.values[n]}, nil
+}
+
+// canonicalEnum checks every value against enum and returns them in their
+// canonical form, for types decoding all the values of a key at once
+func canonicalEnum(enum *enumSet, vals []string) ([]string, error) {
+	out, copied := vals, false
+	for i, val := range vals {
+		n := enum.index(val)
+		if n < 0 {
+			return nil, enum.notAllowed(val)
+		}
+		if enum.values[n] != val {
+			// The values belong to the source, only folded ones are copied
+			if !copied {
+				out, copied = slices.Clone(vals), true
+			}
+			out[i] = enum.values[n]
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,182 @@
+package qparser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// direction is a string enum
+type direction string
+
+func (direction) Values() []string { return []string{"asc", "desc"} }
+
+// priority is an int enum decoded from the index of its name
+type priority uint8
+
+const (
+	priorityLow priority = iota
+	priorityNormal
+	priorityHigh
+)
+
+func (*priority) Values() []string { return []string{"low", "normal", "high"} }
+
+// state is an int enum decoding itself, Values only restricts the names
+type state int
+
+func (*state) Values() []string { return []string{"open", "closed"} }
+
+func (s *state) UnmarshalText(text []byte) error {
+	*s = map[string]state{"open": 1, "closed": 2}[string(text)]
+	return nil
+}
+
+type ticketQuery struct {
+	Sort     direction            `qp:"sort"`
+	SortBy   *direction           `qp:"sort_by,fold"`
+	Format   string               `qp:"format,enum=json|csv,fold"`
+	Fields   []string             `qp:"fields,enum=id|title|owner"`
+	Priority priority             `qp:"priority,default=normal"`
+	Levels   []priority           `qp:"levels,fold"`
+	Limit    int                  `qp:"limit,enum=10|20|50"`
+	State    state                `qp:"state"`
+	Order    map[string]direction `qp:"order,fold"`
+}
+
+func TestEnum(t *testing.T) {
+	t.Run("Allowed", func(t *testing.T) {
+		var got ticketQuery
+		require.NoError(t, Parse(url.Values{
+			"sort":         {"desc"},
+			"sort_by":      {"ASC"},
+			"format":       {"CSV"},
+			"fields":       {"id,title"},
+			"levels":       {"High,low"},
+			"limit":        {"020"},
+			"state":        {"closed"},
+			"order[title]": {"Desc"},
+		}, &got))
+
+		asc := direction("asc")
+		assert.Equal(t, ticketQuery{
+			Sort:     "desc",
+			SortBy:   &asc,
+			Format:   "csv",
+			Fields:   []string{"id", "title"},
+			Priority: priorityNormal,
+			Levels:   []priority{priorityHigh, priorityLow},
+			Limit:    20,
+			State:    2,
+			Order:    map[string]direction{"title": "desc"},
+		}, got)
+	})
+
+	t.Run("Not-Allowed", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			values url.Values
+			field  string
+			msg    string
+		}{
+			{"Type", url.Values{"sort": {"DESC"}}, "Sort", `not allowed: "DESC", want one of asc|desc`},
+			{"Tag", url.Values{"format": {"xml"}}, "Format", `not allowed: "xml", want one of json|csv`},
+			{"Slice-Element", url.Values{"fields": {"id,secret"}}, "Fields", `element [1]: not allowed: "secret", want one of id|title|owner`},
+			{"Indexed", url.Values{"priority": {"2"}}, "Priority", `not allowed: "2", want one of low|normal|high`},
+			{"Number", url.Values{"limit": {"15"}}, "Limit", `not allowed: "15", want one of 10|20|50`},
+			{"Number-Index", url.Values{"limit": {"1"}}, "Limit", `not allowed: "1", want one of 10|20|50`},
+			{"Unmarshaler", url.Values{"state": {"merged"}}, "State", `not allowed: "merged", want one of open|closed`},
+			{"Map-Value", url.Values{"order[title]": {"up"}}, "Order[title]", `not allowed: "up", want one of asc|desc`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got ticketQuery
+				err := Parse(tt.values, &got)
+				assert.ErrorIs(t, err, ErrNotAllowed)
+
+				var fieldErr *FieldError
+				require.ErrorAs(t, err, &fieldErr)
+				assert.Equal(t, "ticketQuery."+tt.field, fieldErr.FieldName)
+				assert.EqualError(t, fieldErr.Err, tt.msg)
+			})
+		}
+	})
+
+	t.Run("Bound-Field", func(t *testing.T) {
+		type params struct {
+			Accept string `header:"Accept,enum=application/json|text/csv"`
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "text/html")
+
+		var got params
+		assert.ErrorIs(t, Bind(req, &got), ErrNotAllowed)
+	})
+
+	t.Run("Encode", func(t *testing.T) {
+		type params struct {
+			Sort     direction  `qp:"sort"`
+			Priority priority   `qp:"priority"`
+			Levels   []priority `qp:"levels"`
+			Limit    int        `qp:"limit,enum=10|20|50"`
+			Ratio    float64    `qp:"ratio,enum=0.5|1|2"`
+		}
+		in := params{Sort: "asc", Priority: priorityHigh, Levels: []priority{priorityLow, priorityNormal}, Limit: 20, Ratio: 0.5}
+		values, err := Encode(in)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"sort":     {"asc"},
+			"priority": {"high"},
+			"levels":   {"low", "normal"},
+			"limit":    {"20"},
+			"ratio":    {"0.5"},
+		}, values)
+
+		var got params
+		require.NoError(t, Parse(values, &got))
+		assert.Equal(t, in, got)
+
+		_, err = Encode(params{Sort: "asc", Priority: 7})
+		assert.ErrorIs(t, err, ErrOutOfRange)
+	})
+
+	t.Run("Invalid-Tags", func(t *testing.T) {
+		tests := []struct {
+			name string
+			dst  any
+		}{
+			{"Empty-Enum", &struct {
+				S string `qp:"s,enum="`
+			}{}},
+			{"Empty-Value", &struct {
+				S string `qp:"s,enum=a||b"`
+			}{}},
+			{"Fold-Without-Enum", &struct {
+				S string `qp:"s,fold"`
+			}{}},
+			{"Bad-Default", &struct {
+				S string `qp:"s,enum=a|b,default=c"`
+			}{}},
+			{"Number-Names", &struct {
+				N int `qp:"n,enum=light|heavy"`
+			}{}},
+			{"Number-Overflow", &struct {
+				N uint8 `qp:"n,enum=1|300"`
+			}{}},
+			{"Struct-Slice", &struct {
+				Items []struct {
+					A int `qp:"a"`
+				} `qp:"items,enum=a"`
+			}{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.ErrorIs(t, Parse(url.Values{}, tt.dst), ErrInvalidTag)
+			})
+		}
+	})
+}
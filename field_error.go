@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -22,15 +23,69 @@ var (
 	// ErrUnsupportedKind indicates that the target type is not supported by the parser.
 	// This typically occurs with complex types like maps, channels, or unsupported structs.
 	ErrUnsupportedKind = errors.New("unsupported kind")
+
+	// ErrMissingValue indicates that a field tagged with the "required" option has
+	// no matching key in the query.
+	ErrMissingValue = errors.New("missing value")
+
+	// ErrEmptyValue indicates that a field tagged with the "nonempty" option has a
+	// matching key in the query, but every value for it is blank (e.g. "?id="),
+	// or that a field tagged with the "nonzero" option decoded to its zero value.
+	ErrEmptyValue = errors.New("empty value")
+
+	// ErrTooSmall indicates that a value is below the "min" option of its field,
+	// or shorter than its "min" or "len" option for strings, slices and maps.
+	ErrTooSmall = errors.New("too small")
+
+	// ErrTooLarge indicates that a value is above the "max" option of its field,
+	// or longer than its "max" or "len" option for strings, slices and maps.
+	ErrTooLarge = errors.New("too large")
+
+	// ErrNotAllowed indicates that a value is not listed by the "oneof" or
+	// "enum" option of its field, or by the Values of its Enum type.
+	ErrNotAllowed = errors.New("not allowed")
+
+	// ErrDuplicateValue indicates that a key was sent several times for a
+	// field decoded from a single value, under the DuplicateError policy.
+	ErrDuplicateValue = errors.New("duplicate value")
+
+	// ErrPatternMismatch indicates that a value does not match the "regex"
+	// option of its field.
+	ErrPatternMismatch = errors.New("pattern mismatch")
+
+	// ErrUnknownParameter indicates that a Decoder in strict mode received query
+	// keys that no field consumes. The keys are listed by UnknownParameterError.
+	ErrUnknownParameter = errors.New("unknown parameter")
+
+	// ErrInvalidTag indicates a malformed struct tag, such as an unknown option or a
+	// default value that cannot be parsed as the field type. It is reported when the
+	// struct metadata is first built and on every subsequent parse of that type.
+	ErrInvalidTag = errors.New("invalid tag")
 )
 
+// FieldError reports a failure to decode a single field. FieldName is the
+// dotted path of the field starting at the root struct type, e.g.
+// "SearchParams.Pagination.Page".
+//
+// Source names the part of the request the field is bound to when decoding
+// with Bind: "query", "path", "header" or "cookie". Decoding a Layered
+// source, it names the layer that supplied the failing value. It is empty
+// otherwise.
+//
+// Constraint is the validation option the decoded value failed, as written in
+// the tag, e.g. "max=100". It is empty when the value could not be decoded.
 type FieldError struct {
-	FieldName string
-	Err       error
+	FieldName  string
+	Source     string
+	Constraint string
+	Err        error
 }
 
 func (e *FieldError) Error() string {
-	if e.FieldName != "" {
+	switch {
+	case e.FieldName != "" && e.Source != "":
+		return fmt.Sprintf("failed to parse %q from %s: %v", e.FieldName, e.Source, e.Err)
+	case e.FieldName != "":
 		return fmt.Sprintf("failed to parse %q: %v", e.FieldName, e.Err)
 	}
 	return e.Err.Error()
@@ -40,6 +95,44 @@ func (e *FieldError) Unwrap() error {
 	return e.Err
 }
 
+// FieldErrors is the list of field failures returned by ParseAll and by a
+// Decoder created with WithAllErrors. It works with errors.Is and errors.As,
+// which inspect every contained FieldError.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, fe := range e {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// UnknownParameterError lists, in sorted order, the query keys a strict
+// Decoder received but no field consumes. It unwraps to ErrUnknownParameter.
+type UnknownParameterError struct {
+	Keys []string
+}
+
+func (e *UnknownParameterError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrUnknownParameter, strings.Join(e.Keys, ", "))
+}
+
+func (e *UnknownParameterError) Unwrap() error {
+	return ErrUnknownParameter
+}
+
 func wrapFieldError(fieldName string, err error) error {
 	if err == nil {
 		return nil
@@ -0,0 +1,48 @@
+package qparser
+
+import (
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+)
+
+// multipartMemory is the part of a multipart body ParseForm keeps in memory,
+// file parts beyond it are stored in temporary files. It is the value
+// net/http uses for FormFile.
+const multipartMemory = 32 << 20
+
+var (
+	fileHeaderType  = reflect.TypeFor[*multipart.FileHeader]()
+	fileHeadersType = reflect.TypeFor[[]*multipart.FileHeader]()
+)
+
+// isFileType reports whether typ receives uploaded files rather than values
+func isFileType(typ reflect.Type) bool {
+	return typ == fileHeaderType || typ == fileHeadersType
+}
+
+// formSource is a source over a parsed request body, also carrying the files
+// of a multipart form
+type formSource struct {
+	ValuesSource
+	files map[string][]*multipart.FileHeader
+}
+
+// readForm parses the body of r, once, limited to d.maxFormSize bytes.
+// Multipart bodies fill r.MultipartForm, both kinds fill r.PostForm.
+func (d *Decoder) readForm(r *http.Request) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	multipartBody := mediaType == "multipart/form-data"
+	if r.PostForm != nil && (!multipartBody || r.MultipartForm != nil) {
+		return nil // already parsed by the caller
+	}
+
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, d.maxFormSize)
+	}
+	if multipartBody {
+		return r.ParseMultipartForm(multipartMemory)
+	}
+	return r.ParseForm()
+}
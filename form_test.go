@@ -0,0 +1,162 @@
+package qparser
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadForm struct {
+	Title  string                  `qp:"title,required"`
+	Tags   []string                `qp:"tags"`
+	Avatar *multipart.FileHeader   `qp:"avatar"`
+	Photos []*multipart.FileHeader `qp:"photos"`
+	Meta   struct {
+		Doc *multipart.FileHeader `qp:"doc,required"`
+	} `qp:"meta"`
+}
+
+type multipartFile struct {
+	field, name, content string
+}
+
+// newMultipartRequest builds a multipart POST request from fields and files
+func newMultipartRequest(t *testing.T, fields url.Values, files ...multipartFile) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for key, vals := range fields {
+		for _, v := range vals {
+			require.NoError(t, w.WriteField(key, v))
+		}
+	}
+	for _, f := range files {
+		part, err := w.CreateFormFile(f.field, f.name)
+		require.NoError(t, err)
+		_, err = io.WriteString(part, f.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload?title=from-query", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestParseForm(t *testing.T) {
+	type params struct {
+		Page  int      `qp:"page"`
+		Query string   `qp:"q"`
+		IDs   []int    `qp:"ids"`
+		Sort  []string `qp:"sort"`
+	}
+
+	t.Run("URL-Encoded", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/?page=9", strings.NewReader("page=2&q=a%26b&ids=1,2&ids=3"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var got params
+		require.NoError(t, ParseForm(req, &got))
+		assert.Equal(t, params{Page: 2, Query: "a&b", IDs: []int{1, 2, 3}}, got)
+	})
+
+	t.Run("Multipart", func(t *testing.T) {
+		req := newMultipartRequest(t, url.Values{"title": {"Holidays"}, "tags": {"sea,sun"}},
+			multipartFile{"avatar", "me.png", "png"},
+			multipartFile{"photos", "1.jpg", "one"},
+			multipartFile{"photos", "2.jpg", "two"},
+			multipartFile{"meta[doc]", "notes.txt", "notes"},
+		)
+
+		var got uploadForm
+		require.NoError(t, ParseForm(req, &got))
+		assert.Equal(t, "Holidays", got.Title)
+		assert.Equal(t, []string{"sea", "sun"}, got.Tags)
+		require.NotNil(t, got.Avatar)
+		assert.Equal(t, "me.png", got.Avatar.Filename)
+		require.Len(t, got.Photos, 2)
+		assert.Equal(t, "2.jpg", got.Photos[1].Filename)
+		require.NotNil(t, got.Meta.Doc)
+
+		f, err := got.Meta.Doc.Open()
+		require.NoError(t, err)
+		defer f.Close()
+		content, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, "notes", string(content))
+	})
+
+	t.Run("Missing-Required-File", func(t *testing.T) {
+		req := newMultipartRequest(t, url.Values{"title": {"x"}})
+
+		var got uploadForm
+		err := ParseForm(req, &got)
+		assert.ErrorIs(t, err, ErrMissingValue)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "uploadForm.Meta.Doc", fieldErr.FieldName)
+	})
+
+	t.Run("Body-Too-Large", func(t *testing.T) {
+		d := NewDecoder(WithMaxFormSize(16))
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("q="+strings.Repeat("a", 64)))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var got params
+		var maxErr *http.MaxBytesError
+		assert.ErrorAs(t, d.ParseForm(req, &got), &maxErr)
+
+		req = newMultipartRequest(t, nil, multipartFile{"avatar", "big.bin", strings.Repeat("x", 64)})
+		var upload uploadForm
+		assert.ErrorAs(t, d.ParseForm(req, &upload), &maxErr)
+	})
+
+	t.Run("Already-Parsed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("page=4"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		require.NoError(t, req.ParseForm())
+
+		var got params
+		require.NoError(t, NewDecoder(WithMaxFormSize(1)).ParseForm(req, &got))
+		assert.Equal(t, 4, got.Page)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		req := newMultipartRequest(t, url.Values{"title": {"x"}},
+			multipartFile{"meta[doc]", "a.txt", "a"},
+			multipartFile{"unexpected", "b.txt", "b"},
+		)
+
+		var got uploadForm
+		err := NewDecoder(WithStrict()).ParseForm(req, &got)
+
+		var unknownErr *UnknownParameterError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, []string{"unexpected"}, unknownErr.Keys)
+	})
+
+	t.Run("File-Options", func(t *testing.T) {
+		type bad struct {
+			File *multipart.FileHeader `qp:"file,default=x"`
+		}
+		req := newMultipartRequest(t, nil)
+
+		var got bad
+		assert.ErrorIs(t, ParseForm(req, &got), ErrInvalidTag)
+	})
+
+	t.Run("Encode-Skips-Files", func(t *testing.T) {
+		values, err := Encode(uploadForm{Title: "x", Avatar: &multipart.FileHeader{Filename: "a"}})
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"title": {"x"}}, values)
+	})
+}
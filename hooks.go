@@ -0,0 +1,124 @@
+package qparser
+
+import (
+	"reflect"
+	"runtime"
+	"slices"
+)
+
+// BeforeParser is implemented by structs preparing themselves before their
+// fields are decoded, e.g. to set defaults computed at runtime. BeforeParse
+// is called on the destination struct and on every nested struct, parents
+// first, including the elements of slices of structs. Hooks promoted from an
+// embedded struct are only called on that struct, not again on its parent.
+type BeforeParser interface {
+	BeforeParse()
+}
+
+// AfterParser is implemented by structs checking or normalizing themselves
+// once their fields are decoded, e.g. for cross-field rules such as "from
+// must be before to". AfterParse is called on the destination struct and on
+// every nested struct, children first, so a parent sees its nested structs
+// complete.
+//
+// A non-nil error is reported as a FieldError naming the struct, e.g.
+// "Search.Range". AfterParse is not called once a field failed to decode.
+type AfterParser interface {
+	AfterParse() error
+}
+
+var (
+	beforeParserType = reflect.TypeFor[BeforeParser]()
+	afterParserType  = reflect.TypeFor[AfterParser]()
+)
+
+// structHooks reports which of BeforeParser and AfterParser *rt implements,
+// given info, the metadata of rt. A hook promoted from an embedded struct
+// decoded as a nested one is left out, the steps of that struct call it.
+func structHooks(rt reflect.Type, info *structInfo) (before, after bool) {
+	pt := reflect.PointerTo(rt)
+	before = pt.Implements(beforeParserType) && !promotedFromNested(rt, info, "BeforeParse")
+	after = pt.Implements(afterParserType) && !promotedFromNested(rt, info, "AfterParse")
+	return before, after
+}
+
+// promotedFromNested reports whether the method name of *rt is promoted from
+// an embedded field that info decodes as a nested struct
+func promotedFromNested(rt reflect.Type, info *structInfo, name string) bool {
+	i := promotedFrom(rt, name)
+	return i >= 0 && slices.ContainsFunc(info.fields, func(f fieldInfo) bool {
+		return f.isNested && len(f.index) == 1 && f.index[0] == i
+	})
+}
+
+// maxEmbedDepth bounds the search of promoted methods, which would not end on
+// a struct embedding a pointer to itself
+const maxEmbedDepth = 16
+
+// promotedFrom returns the index of the embedded field of rt the method name
+// of *rt is promoted from, -1 when rt declares it
+func promotedFrom(rt reflect.Type, name string) int {
+	if declares(rt, name) {
+		return -1
+	}
+	// The shallowest embedded field holding the method wins, like selectors do
+	field, depth := -1, 0
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		if !f.Anonymous {
+			continue
+		}
+		if d := methodDepth(f.Type, name, 0); d >= 0 && (field < 0 || d < depth) {
+			field, depth = i, d
+		}
+	}
+	return field
+}
+
+// methodDepth returns how many embedded fields below t, through pointers, the
+// method name is declared, counting from depth, -1 if it is not found
+func methodDepth(t reflect.Type, name string, depth int) int {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		if _, ok := t.MethodByName(name); ok {
+			return depth
+		}
+		return -1
+	}
+	if declares(t, name) {
+		return depth
+	}
+	if t.Kind() != reflect.Struct || depth == maxEmbedDepth {
+		return -1
+	}
+	found := -1
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.Anonymous {
+			continue
+		}
+		if d := methodDepth(f.Type, name, depth+1); d >= 0 && (found < 0 || d < found) {
+			found = d
+		}
+	}
+	return found
+}
+
+// declares reports whether t declares the method name, with a value or a
+// pointer receiver. Reflection lists promoted methods too, but those are
+// wrappers generated by the compiler, without a source file.
+func declares(t reflect.Type, name string) bool {
+	for _, mt := range []reflect.Type{t, reflect.PointerTo(t)} {
+		m, ok := mt.MethodByName(name)
+		if !ok {
+			continue
+		}
+		pc := m.Func.Pointer()
+		if file, _ := runtime.FuncForPC(pc).FileLine(pc); file != "<autogenerated>" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package qparser
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookLog records hook calls across the structs of a decode
+type hookLog []string
+
+type hookedWindow struct {
+	From int `qp:"from"`
+	To   int `qp:"to"`
+	log  *hookLog
+}
+
+func (w *hookedWindow) BeforeParse() {
+	if w.log != nil {
+		*w.log = append(*w.log, "before window")
+	}
+}
+
+var errInvertedWindow = errors.New("from is after to")
+
+func (w *hookedWindow) AfterParse() error {
+	if w.log != nil {
+		*w.log = append(*w.log, "after window")
+	}
+	if w.From > w.To {
+		return errInvertedWindow
+	}
+	return nil
+}
+
+type hookedItem struct {
+	Name string `qp:"name"`
+}
+
+// AfterParse has a value receiver, called through the element pointer
+func (i hookedItem) AfterParse() error {
+	if i.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type hookedSearch struct {
+	Query  string        `qp:"q"`
+	Page   int           `qp:"page"`
+	Window hookedWindow  `qp:"w"`
+	Range  *hookedWindow `qp:"r"`
+	Items  []hookedItem  `qp:"items"`
+	log    *hookLog
+}
+
+func (s *hookedSearch) BeforeParse() {
+	*s.log = append(*s.log, "before search")
+	s.Window.log = s.log
+	if s.Page == 0 {
+		s.Page = 1
+	}
+}
+
+func (s *hookedSearch) AfterParse() error {
+	*s.log = append(*s.log, "after search")
+	if s.Query == "forbidden" {
+		return &FieldError{FieldName: "Query", Err: ErrNotAllowed}
+	}
+	return nil
+}
+
+// HookedPaging is embedded, promoting its hooks to the structs embedding it
+type HookedPaging struct {
+	Cursor string `qp:"cursor"`
+	log    *hookLog
+}
+
+func (p *HookedPaging) BeforeParse() {
+	*p.log = append(*p.log, "before paging")
+}
+
+func (p *HookedPaging) AfterParse() error {
+	*p.log = append(*p.log, "after paging")
+	return nil
+}
+
+type hookedList struct {
+	HookedPaging
+	Q string `qp:"q"`
+}
+
+// hookedFeed declares its own AfterParse, shadowing the promoted one
+type hookedFeed struct {
+	*HookedPaging
+	Q string `qp:"q"`
+}
+
+func (f *hookedFeed) AfterParse() error {
+	*f.log = append(*f.log, "after feed")
+	return nil
+}
+
+type hookedSkipped struct {
+	HookedPaging `qp:"-"`
+	Q            string `qp:"q"`
+}
+
+func TestHooks(t *testing.T) {
+	newSearch := func() *hookedSearch {
+		return &hookedSearch{log: new(hookLog)}
+	}
+
+	t.Run("Order", func(t *testing.T) {
+		got := newSearch()
+		require.NoError(t, Parse(url.Values{"q": {"shoes"}, "w[from]": {"1"}, "w[to]": {"2"}}, got))
+		assert.Equal(t, hookLog{"before search", "before window", "after window", "after search"}, *got.log)
+		assert.Equal(t, 1, got.Page, "set by BeforeParse")
+		require.NotNil(t, got.Range, "allocated before its hooks")
+	})
+
+	t.Run("Embedded", func(t *testing.T) {
+		values := url.Values{"q": {"shoes"}, "cursor": {"abc"}}
+
+		list := &hookedList{HookedPaging: HookedPaging{log: new(hookLog)}}
+		require.NoError(t, Parse(values, list))
+		assert.Equal(t, hookLog{"before paging", "after paging"}, *list.log, "promoted hooks are called once")
+
+		feed := &hookedFeed{HookedPaging: &HookedPaging{log: new(hookLog)}}
+		require.NoError(t, Parse(values, feed))
+		assert.Equal(t, hookLog{"before paging", "after paging", "after feed"}, *feed.log)
+
+		skipped := &hookedSkipped{HookedPaging: HookedPaging{log: new(hookLog)}}
+		require.NoError(t, Parse(values, skipped))
+		assert.Equal(t, hookLog{"before paging", "after paging"}, *skipped.log, "called through the parent")
+		assert.Empty(t, skipped.Cursor)
+	})
+
+	t.Run("Nested-Error", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			values url.Values
+			field  string
+		}{
+			{"Struct", url.Values{"w[from]": {"3"}, "w[to]": {"2"}}, "hookedSearch.Window"},
+			{"Pointer", url.Values{"r[from]": {"3"}}, "hookedSearch.Range"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := Parse(tt.values, newSearch())
+				assert.ErrorIs(t, err, errInvertedWindow)
+				assert.EqualError(t, err, `failed to parse "`+tt.field+`": from is after to`)
+			})
+		}
+	})
+
+	t.Run("Root-Error", func(t *testing.T) {
+		err := Parse(url.Values{"q": {"forbidden"}}, newSearch())
+		assert.ErrorIs(t, err, ErrNotAllowed)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "hookedSearch", fieldErr.FieldName, "a returned FieldError is wrapped")
+	})
+
+	t.Run("Struct-Slice-Element", func(t *testing.T) {
+		err := Parse(url.Values{"items[0][name]": {"a"}, "items[1][other]": {"b"}, "items[1][name]": {""}}, newSearch())
+		assert.EqualError(t, err, `failed to parse "hookedSearch.Items[1]": name is required`)
+	})
+
+	t.Run("Skipped-After-Failure", func(t *testing.T) {
+		got := newSearch()
+		err := ParseAll(url.Values{"page": {"x"}, "w[from]": {"3"}, "w[to]": {"2"}}, got)
+
+		var fieldErrs FieldErrors
+		require.ErrorAs(t, err, &fieldErrs)
+		require.Len(t, fieldErrs, 1)
+		assert.Equal(t, "hookedSearch.Page", fieldErrs[0].FieldName)
+		assert.Equal(t, hookLog{"before search", "before window"}, *got.log)
+	})
+}
@@ -0,0 +1,183 @@
+package gentest
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/prawirdani/qparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ qparser.QueryDecoder = (*Search)(nil)
+	_ qparser.QueryDecoder = (*Order)(nil)
+)
+
+// reflective decodes without the generated methods
+var reflective = qparser.NewDecoder(qparser.WithoutGenerated())
+
+var sentinels = []error{
+	qparser.ErrInvalidValue,
+	qparser.ErrOutOfRange,
+	qparser.ErrMissingValue,
+	qparser.ErrEmptyValue,
+	qparser.ErrUnsupportedKind,
+}
+
+// assertSameDecode parses query with the generated decoder and the reflective
+// one into fresh values of T, and asserts identical results and errors.
+func assertSameDecode[T any](t *testing.T, query string) {
+	t.Helper()
+	values, err := url.ParseQuery(query)
+	require.NoError(t, err)
+
+	var generated, want T
+	genErr := qparser.Parse(values, &generated)
+	wantErr := reflective.Parse(values, &want)
+
+	assert.Equal(t, want, generated)
+	if wantErr == nil {
+		assert.NoError(t, genErr)
+		return
+	}
+	require.Error(t, genErr)
+	assert.Equal(t, wantErr.Error(), genErr.Error())
+	for _, sentinel := range sentinels {
+		assert.Equal(t, errors.Is(wantErr, sentinel), errors.Is(genErr, sentinel), "errors.Is %v", sentinel)
+	}
+
+	var wantFE, genFE *qparser.FieldError
+	require.ErrorAs(t, wantErr, &wantFE)
+	require.ErrorAs(t, genErr, &genFE)
+	assert.Equal(t, wantFE.FieldName, genFE.FieldName)
+}
+
+func TestSearchMatchesReflection(t *testing.T) {
+	queries := []string{
+		"",
+		"q=shoes&page=3&size=50&score=4.5&ratio=0.25&exact=true",
+		"q=&q=second",
+		"tiny=-128&small=255",
+		"since=2024-01-02&before=2024-01-02T15:04:05Z",
+		"since=2024-01-02T15:04:05.123456789%2B07:00",
+		"status=closed&owner=42",
+		"tags=a,b&tags=c&tags=%20d%20,,",
+		"tags=,,",
+		`tags=a\,b,c\\,d\x`,
+		`ids=1\,2`,
+		"ids=1,2,3&weights=0.1",
+		"limit=5&labels=x,y&opt=1,2",
+		"limit=",
+		"sort[by]=name&sort[desc]=1&sort[order][nulls]=last",
+		"range[from]=10&range[to]=20",
+		"range[from]=",
+		"cursor=abc",
+		"Ignored=x&Untagged=y&internal=z",
+
+		// failures
+		"page=abc",
+		"page=99999999999999999999",
+		"size=70000",
+		"size=-1",
+		"score=NaNx",
+		"exact=maybe",
+		"tiny=128",
+		"small=256",
+		"since=yesterday",
+		"owner=1.5",
+		"ids=1,x,3",
+		"weights=1,2,bad",
+		"limit=nope",
+		"opt=1,,x",
+		"sort[desc]=nah",
+		"range[from]=x",
+		"range[to]=9223372036854775808",
+		"range[from]=30&range[to]=20",
+		"cursor=",
+		"cursor=%20&cursor=",
+		"page=1&size=bad&score=bad",
+	}
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			assertSameDecode[Search](t, query)
+		})
+	}
+}
+
+func TestOrderMatchesReflection(t *testing.T) {
+	queries := []string{
+		"id=1&customer=bob",
+		"id=1&customer=bob&items=1,2&note=rush&notify=false",
+		"id=1&customer=bob&note=",
+		"id=1&customer=bob&items=4294967296",
+		"id=1&customer=bob&items=",
+		"id=1&customer=bob&notify=",
+		"customer=bob",
+		"id=1",
+		"id=1&customer=%20",
+		"id=x&customer=bob",
+	}
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			assertSameDecode[Order](t, query)
+		})
+	}
+}
+
+func TestGeneratedKeepsExistingValues(t *testing.T) {
+	limit := 7
+	search := Search{Query: "kept", Limit: &limit, Range: &Range{To: 3}}
+	want := search
+	want.Range = &Range{To: 3}
+
+	require.NoError(t, qparser.Parse(url.Values{"page": {"2"}}, &search))
+	require.NoError(t, reflective.Parse(url.Values{"page": {"2"}}, &want))
+	assert.Equal(t, want, search)
+	assert.Equal(t, "kept", search.Query)
+	assert.Equal(t, int64(3), search.Range.To)
+}
+
+func TestParseQueryStringUsesGenerated(t *testing.T) {
+	var generated, want Search
+	query := "q=a%2Bb&ids=1,2&sort[by]=name"
+	require.NoError(t, qparser.ParseQueryString(query, &generated))
+	require.NoError(t, reflective.ParseQueryString(query, &want))
+	assert.Equal(t, want, generated)
+}
+
+func TestPromotedHooksCalledOnce(t *testing.T) {
+	var generated, want Search
+	require.NoError(t, qparser.Parse(url.Values{"cursor": {"abc"}}, &generated))
+	require.NoError(t, reflective.Parse(url.Values{"cursor": {"abc"}}, &want))
+	assert.Equal(t, 1, generated.checks)
+	assert.Equal(t, 1, want.checks)
+}
+
+func TestGeneratedRejectsNilPointer(t *testing.T) {
+	want := reflective.Parse(url.Values{"q": {"a"}}, (*Search)(nil))
+	require.Error(t, want)
+
+	assert.Equal(t, want, qparser.Parse(url.Values{"q": {"a"}}, (*Search)(nil)))
+	assert.Equal(t, want, qparser.ParseQueryString("q=a", (*Search)(nil)))
+}
+
+func BenchmarkSearch(b *testing.B) {
+	values, _ := url.ParseQuery("q=shoes&page=3&ids=1,2,3&since=2024-01-02&sort[by]=name&range[to]=20&cursor=abc")
+
+	b.Run("Generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var s Search
+			_ = qparser.Parse(values, &s)
+		}
+	})
+	b.Run("Reflective", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var s Search
+			_ = reflective.Parse(values, &s)
+		}
+	})
+}
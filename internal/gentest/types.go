@@ -0,0 +1,100 @@
+// Package gentest holds structs decoded by code generated with qparsergen,
+// and checks that the generated decoders behave like the reflective one.
+package gentest
+
+import (
+	"errors"
+	"time"
+)
+
+//go:generate go run ../../cmd/qparsergen -type Search,Order
+
+// Status is a named string decoded through its underlying type
+type Status string
+
+// ID is a named integer decoded through its underlying type
+type ID int64
+
+// Search exercises every field shape qparsergen supports
+type Search struct {
+	Query    string     `qp:"q"`
+	Page     int        `qp:"page,default=1"`
+	Size     uint16     `qp:"size,default=20"`
+	Score    float64    `qp:"score"`
+	Ratio    float32    `qp:"ratio"`
+	Exact    bool       `qp:"exact"`
+	Tiny     int8       `qp:"tiny"`
+	Small    uint8      `qp:"small"`
+	Since    time.Time  `qp:"since"`
+	Status   Status     `qp:"status,default=open"`
+	Owner    ID         `qp:"owner"`
+	Tags     []string   `qp:"tags,omitempty"`
+	IDs      []ID       `qp:"ids"`
+	Weights  []float64  `qp:"weights,default=0.5|1.5"`
+	Limit    *int       `qp:"limit"`
+	Before   *time.Time `qp:"before"`
+	Labels   *[]string  `qp:"labels"`
+	Optional []*int     `qp:"opt"`
+	Sort     Sort       `qp:"sort"`
+	Range    *Range     `qp:"range"`
+	Paging
+	Ignored  string `qp:"-"`
+	Untagged string
+	internal string
+}
+
+// Sort is a nested struct namespaced under its parent key, e.g. sort[by]
+type Sort struct {
+	By    string `qp:"by,default=created_at"`
+	Desc  bool   `qp:"desc"`
+	Order Inner  `qp:"order"`
+}
+
+// Inner is nested two levels deep, e.g. sort[order][nulls]
+type Inner struct {
+	Nulls string `qp:"nulls"`
+}
+
+// BeforeParse sets a default the query can override
+func (i *Inner) BeforeParse() {
+	if i.Nulls == "" {
+		i.Nulls = "first"
+	}
+}
+
+// Range is reached through a pointer, always allocated like Parse does
+type Range struct {
+	From *int64 `qp:"from"`
+	To   int64  `qp:"to"`
+}
+
+var errInvertedRange = errors.New("from is after to")
+
+// AfterParse checks a rule spanning both fields
+func (r *Range) AfterParse() error {
+	if r.From != nil && *r.From > r.To {
+		return errInvertedRange
+	}
+	return nil
+}
+
+// Paging is embedded without a tag, its keys are not prefixed
+type Paging struct {
+	Cursor string `qp:"cursor,nonempty"`
+	checks int    // calls of AfterParse
+}
+
+// AfterParse is promoted to Search, but only called on Paging
+func (p *Paging) AfterParse() error {
+	p.checks++
+	return nil
+}
+
+// Order exercises the required and nonempty options
+type Order struct {
+	ID       ID       `qp:"id,required"`
+	Customer string   `qp:"customer,required,nonempty"`
+	Items    []uint32 `qp:"items,nonempty"`
+	Note     *string  `qp:"note,default=none"`
+	Notify   bool     `qp:"notify,default=true"`
+}
@@ -0,0 +1,99 @@
+package qparser
+
+import "strings"
+
+// Notation selects how compound query keys, such as map entries, are written.
+type Notation uint8
+
+const (
+	// BracketNotation writes compound keys as filter[status]. It is the default.
+	BracketNotation Notation = iota
+
+	// DotNotation writes compound keys as filter.status.
+	DotNotation
+)
+
+// subKey composes the key of the entry sub under prefix
+func (d *Decoder) subKey(prefix, sub string) string {
+	if d.notation == DotNotation {
+		return prefix + "." + sub
+	}
+	return prefix + "[" + sub + "]"
+}
+
+// fullKey resolves the key of a field cached relative to a slice element
+// against base, the runtime key of that element (e.g. items[0]). Outside of
+// slice elements base is empty and key is returned unchanged.
+func (d *Decoder) fullKey(base, key string) string {
+	switch {
+	case base == "":
+		return key
+	case key == "":
+		return base
+	case d.notation == DotNotation:
+		return base + "." + key
+	}
+
+	// Only the first segment of a relative key is unbracketed: addr[city]
+	if i := strings.IndexByte(key, '['); i > 0 {
+		return base + "[" + key[:i] + "]" + key[i:]
+	}
+	return base + "[" + key + "]"
+}
+
+// splitIndexKey reports whether key addresses a field of an element of the
+// struct slice at prefix, e.g. items[3][name] or items[3].name under
+// DotNotation, and returns the element index digits. Indices must be written
+// canonically, without sign or leading zeros.
+func (d *Decoder) splitIndexKey(key, prefix string) (string, bool) {
+	if len(key) <= len(prefix)+3 || !strings.HasPrefix(key, prefix) || key[len(prefix)] != '[' {
+		return "", false
+	}
+	rest := key[len(prefix)+1:]
+
+	end := strings.IndexByte(rest, ']')
+	if end <= 0 || end+1 >= len(rest) {
+		return "", false
+	}
+	digits := rest[:end]
+	if len(digits) > 1 && digits[0] == '0' {
+		return "", false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return "", false
+		}
+	}
+
+	next := rest[end+1]
+	if (d.notation == DotNotation && next != '.') || (d.notation != DotNotation && next != '[') {
+		return "", false
+	}
+	return digits, true
+}
+
+// splitSubKey reports whether key addresses an entry under prefix and returns
+// the entry name. The name must be non-empty, and with BracketNotation it may
+// not contain brackets itself.
+func (d *Decoder) splitSubKey(key, prefix string) (string, bool) {
+	if len(key) <= len(prefix)+1 || !strings.HasPrefix(key, prefix) {
+		return "", false
+	}
+	rest := key[len(prefix):]
+
+	if d.notation == DotNotation {
+		if rest[0] != '.' {
+			return "", false
+		}
+		return rest[1:], true
+	}
+
+	if rest[0] != '[' || rest[len(rest)-1] != ']' {
+		return "", false
+	}
+	sub := rest[1 : len(rest)-1]
+	if sub == "" || strings.ContainsAny(sub, "[]") {
+		return "", false
+	}
+	return sub, true
+}
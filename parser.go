@@ -2,264 +2,412 @@ package qparser
 
 import (
 	"fmt"
+	"mime/multipart"
+	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
-	"time"
+	"strings"
 )
 
-// parseStruct traverses struct fields and maps query parameters to field values
-func parseStruct(query map[string][]string, rv reflect.Value, rt reflect.Type) error {
-	info := getStructCache(rt)
-	if info.hasUnexportedWithTag {
-		return ErrUnexportedStruct
-	}
+// decodeState carries the per-call state of a single decode
+type decodeState struct {
+	query   KeySource
+	collect bool        // keep going after a field fails
+	errs    FieldErrors // failures gathered when collect is set
 
-	for _, field := range info.fields {
-		if field.isNested {
-			if err := parseNestedField(query, rv, field, info.name); err != nil {
-				return err
-			}
-			continue
-		}
+	// consumed records every query key bound to a field. It is only
+	// allocated when the caller needs to know about the others.
+	consumed map[string]struct{}
+	remain   []reflect.Value // remain fields met, filled once decoding is done
 
-		vals, ok := query[field.tag]
-		if !ok {
-			continue
-		}
+	files map[string][]*multipart.FileHeader // uploaded files, see ParseForm
 
-		fv := rv.FieldByIndex(field.index)
-		if err := setFieldValue(fv, field.typ, vals); err != nil {
-			return wrapFieldError(fmt.Sprintf("%s.%s", info.name, field.name), err)
-		}
+	// bound holds the non-query sources of Bind, indexed by origin. Fields
+	// bound to a nil source are skipped. binding makes failures carry the
+	// origin of their field as FieldError.Source.
+	bound   [numOrigins]Source
+	binding bool
+}
+
+// consume marks key as bound to a field, in the form the query enumerates
+// it as well when that differs, see keyNormalizer
+func (st *decodeState) consume(key string) {
+	if st.consumed == nil {
+		return
+	}
+	st.consumed[key] = struct{}{}
+	if n, ok := st.query.(keyNormalizer); ok {
+		st.consumed[n.normalizeKey(key)] = struct{}{}
 	}
-	return nil
 }
 
-// parseNestedField handles embedded or nested struct fields
-func parseNestedField(query map[string][]string, rv reflect.Value, field fieldInfo, parentName string) error {
-	fv := rv.FieldByIndex(field.index)
-	ft := field.typ
+// fillRemain sets every remain field met during decoding to the query keys
+// no field consumed. Remain fields stay nil when there are none. Keys they
+// capture count as consumed.
+func (st *decodeState) fillRemain() {
+	if len(st.remain) == 0 {
+		return
+	}
 
-	// Handle pointer to struct
-	if ft.Kind() == reflect.Ptr {
-		if fv.IsNil() {
-			fv.Set(reflect.New(ft.Elem()))
+	// Loop bodies over Keys are closures, see parseStructSliceField
+	query, consumed := st.query, st.consumed
+	var rest url.Values
+	for key := range query.Keys() {
+		if _, ok := consumed[key]; ok {
+			continue
+		}
+		if rest == nil {
+			rest = make(url.Values)
 		}
-		fv = fv.Elem()
-		ft = ft.Elem()
+		vals, _ := query.Lookup(key)
+		rest[key] = slices.Clone(vals)
+		consumed[key] = struct{}{}
 	}
-
-	if err := parseStruct(query, fv, ft); err != nil {
-		return wrapFieldError(fmt.Sprintf("%s.%s", parentName, field.name), err)
+	if rest == nil {
+		return
 	}
-	return nil
-}
 
-// setFieldValue routes to the appropriate handler based on field type
-func setFieldValue(fv reflect.Value, ft reflect.Type, vals []string) error {
-	switch ft.Kind() {
-	case reflect.Ptr:
-		return setPtrField(fv, ft.Elem(), vals)
-	case reflect.Slice:
-		return setSliceField(fv, ft, vals)
-	default:
-		if len(vals) == 0 {
-			return nil
+	for _, fv := range st.remain {
+		m := reflect.MakeMapWithSize(fv.Type(), len(rest))
+		for key, vals := range rest {
+			m.SetMapIndex(reflect.ValueOf(key).Convert(fv.Type().Key()), reflect.ValueOf(slices.Clone(vals)))
 		}
-		return setSingleValue(vals[0], fv, ft)
+		fv.Set(m)
 	}
 }
 
-// setPtrField handles pointer fields, including *[]T
-func setPtrField(fv reflect.Value, elemType reflect.Type, vals []string) error {
-	if elemType.Kind() == reflect.Slice {
-		slice, err := parseSliceFromStrings(vals, elemType)
-		if err != nil {
-			return err
+// fail records err against the field at path. In collect mode the error is
+// kept and nil is returned so decoding continues, otherwise it is returned.
+func (st *decodeState) fail(path string, err error) error {
+	return st.failFrom(originQuery, "", path, err)
+}
+
+// failFrom is fail for a field bound to origin, decoded from the values of
+// key when known. The error tells where they came from, see FieldError.Source.
+func (st *decodeState) failFrom(o origin, key, path string, err error) error {
+	fieldErr, ok := err.(*FieldError)
+	if !ok {
+		fieldErr = &FieldError{FieldName: path, Err: err}
+		if ce, ok := err.(*constraintError); ok {
+			fieldErr.Constraint, fieldErr.Err = ce.constraint, ce.err
 		}
-		if slice.Len() == 0 {
-			return nil
+		switch {
+		case st.binding:
+			fieldErr.Source = o.String()
+		case key != "":
+			if named, ok := st.query.(layerNamer); ok {
+				fieldErr.Source = named.layerOf(key)
+			}
 		}
-		ptr := reflect.New(elemType)
-		ptr.Elem().Set(slice)
-		fv.Set(ptr)
-		return nil
 	}
-
-	if len(vals) == 0 || vals[0] == "" {
+	if st.collect {
+		st.errs = append(st.errs, fieldErr)
 		return nil
 	}
+	return fieldErr
+}
 
-	elemVal := reflect.New(elemType)
-	if err := setSingleValue(vals[0], elemVal.Elem(), elemType); err != nil {
-		return err
+// joinPath appends a field name to the dotted path of its parent. An empty
+// name stands for the parent itself.
+func joinPath(path, name string) string {
+	switch {
+	case path == "":
+		return name
+	case name == "":
+		return path
 	}
-	fv.Set(elemVal)
-	return nil
+	return path + "." + name
 }
 
-// setSliceField handles slice fields
-func setSliceField(fv reflect.Value, ft reflect.Type, vals []string) error {
-	// Parse directly from comma-separated values without splitting
-	slice, err := parseSliceFromStrings(vals, ft)
-	if err != nil {
-		return err
+// parseStruct runs the decode plan of rt, mapping query parameters to the
+// fields of rv and of its nested structs. prefix namespaces the keys of rv's
+// fields (see getStructCache), base is the runtime key of the enclosing slice
+// element, if any (see fullKey), and path is the dotted name of rv used in
+// FieldError, starting at the root type name.
+func (d *Decoder) parseStruct(st *decodeState, rv reflect.Value, rt reflect.Type, prefix, base, path string) error {
+	info := d.getStructCache(rt, prefix)
+	if info.hasUnexportedWithTag {
+		return ErrUnexportedStruct
 	}
-	if slice.Len() == 0 {
-		return nil
+	if info.err != nil {
+		return info.err
 	}
-	fv.Set(slice)
-	return nil
-}
 
-// setSingleValue parses a single value and sets it on the reflect.Value
-func setSingleValue(val string, fv reflect.Value, typ reflect.Type) error {
-	// No look up table, just raw dog switch for maximum perf
-	// WARN: mega switch for raw performance. Maintain with care.
-	switch typ.Kind() {
-	// ----- Pointers -----
-	case reflect.Ptr:
-		elemType := typ.Elem()
-		elemVal := reflect.New(elemType)
-		if err := setSingleValue(val, elemVal.Elem(), elemType); err != nil {
-			return err
-		}
-		fv.Set(elemVal)
+	for i := range info.steps {
+		step := &info.steps[i]
+		field := step.field
 
-	// ----- Special structs -----
-	case reflect.Struct:
-		if typ == reflect.TypeOf(time.Time{}) {
-			t, err := parseTime(val)
-			if err != nil {
+		switch step.kind {
+		case stepAlloc:
+			if fv := rv.FieldByIndex(step.index); fv.IsNil() {
+				fv.Set(reflect.New(field.typ.Elem()))
+			}
+			continue
+		case stepFail:
+			// Field failures come back already wrapped with their full path,
+			// anything else concerns the nested struct as a whole.
+			if err := st.fail(joinPath(path, step.path), step.err); err != nil {
+				return err
+			}
+			continue
+		case stepRemain:
+			st.remain = append(st.remain, rv.FieldByIndex(step.index))
+			continue
+		case stepFile:
+			if err := d.parseFileField(st, rv.FieldByIndex(step.index), field, d.fullKey(base, field.key), joinPath(path, step.path)); err != nil {
+				return err
+			}
+			continue
+		case stepMap:
+			fv := rv.FieldByIndex(step.index)
+			if err := d.parseMapField(st, fv, field, d.fullKey(base, field.key), joinPath(path, step.path)); err != nil {
 				return err
 			}
-			fv.Set(reflect.ValueOf(t))
-			return nil
+			continue
+		case stepStructSlice:
+			fv := rv.FieldByIndex(step.index)
+			if err := d.parseStructSliceField(st, fv, field, d.fullKey(base, field.key), joinPath(path, step.path)); err != nil {
+				return err
+			}
+			continue
+		case stepBefore:
+			hookTarget(rv, step).(BeforeParser).BeforeParse()
+			continue
+		case stepAfter:
+			if len(st.errs) > 0 {
+				continue // the struct may be partially decoded
+			}
+			if err := hookTarget(rv, step).(AfterParser).AfterParse(); err != nil {
+				// Wrapped even when err is a FieldError, which fail passes through
+				structPath := joinPath(path, step.path)
+				if err := st.fail(structPath, &FieldError{FieldName: structPath, Err: err}); err != nil {
+					return err
+				}
+			}
+			continue
 		}
-		return fmt.Errorf("%w: %v", ErrUnsupportedKind, typ.Kind())
-
-	// ----- Strings -----
-	case reflect.String:
-		fv.SetString(val)
 
-	// ----- Booleans -----
-	case reflect.Bool:
-		b, err := strconv.ParseBool(val)
-		if err != nil {
-			return ErrInvalidValue
+		var src Source = st.query
+		key := d.fullKey(base, field.key)
+		if field.origin != originQuery {
+			// Bound keys are never prefixed
+			if src, key = st.bound[field.origin], field.key; src == nil {
+				continue
+			}
 		}
-		fv.SetBool(b)
 
-	// ----- Signed integers -----
-	case reflect.Int:
-		n, err := strconv.ParseInt(val, 10, strconv.IntSize)
-		if err != nil {
-			return strconvNumError(err, val)
+		vals, ok := src.Lookup(key)
+		switch {
+		case !ok && field.required:
+			if err := st.failFrom(field.origin, key, joinPath(path, step.path), fmt.Errorf("%w: %q", ErrMissingValue, key)); err != nil {
+				return err
+			}
+			continue
+		case !ok:
+			if field.defaults == nil {
+				continue
+			}
+			vals = field.defaults
+		default:
+			if field.origin == originQuery {
+				st.consume(key)
+			}
+			if field.nonempty && isBlank(vals) {
+				if err := st.failFrom(field.origin, key, joinPath(path, step.path), fmt.Errorf("%w: %q", ErrEmptyValue, key)); err != nil {
+					return err
+				}
+				continue
+			}
 		}
-		fv.SetInt(n)
 
-	case reflect.Int8:
-		n, err := strconv.ParseInt(val, 10, 8)
-		if err != nil {
-			return strconvNumError(err, val)
+		fv := rv.FieldByIndex(step.index)
+		err := field.set(fv, vals)
+		if err == nil && field.rules != nil {
+			err = field.checkRules(fv)
 		}
-		fv.SetInt(n)
-
-	case reflect.Int16:
-		n, err := strconv.ParseInt(val, 10, 16)
 		if err != nil {
-			return strconvNumError(err, val)
+			if err := st.failFrom(field.origin, key, joinPath(path, step.path), err); err != nil {
+				return err
+			}
 		}
-		fv.SetInt(n)
+	}
+	return nil
+}
 
-	case reflect.Int32:
-		n, err := strconv.ParseInt(val, 10, 32)
-		if err != nil {
-			return strconvNumError(err, val)
-		}
-		fv.SetInt(n)
+// hookTarget returns a pointer to the struct a hook step is called on, rv or
+// one of its nested structs, allocated by then
+func hookTarget(rv reflect.Value, step *planStep) any {
+	sv := rv.FieldByIndex(step.index)
+	if sv.Kind() == reflect.Ptr {
+		return sv.Interface()
+	}
+	return sv.Addr().Interface()
+}
 
-	case reflect.Int64:
-		n, err := strconv.ParseInt(val, 10, 64)
-		if err != nil {
-			return strconvNumError(err, val)
+// isBlank reports whether every value is empty or whitespace only
+func isBlank(vals []string) bool {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return false
 		}
-		fv.SetInt(n)
+	}
+	return true
+}
 
-	// ----- Unsigned integers -----
-	case reflect.Uint:
-		n, err := strconv.ParseUint(val, 10, strconv.IntSize)
-		if err != nil {
-			return strconvNumError(err, val)
+// parseStructSliceField fills a []S or []*S field from indexed keys such as
+// items[0][name]=a&items[1][name]=b. Elements are ordered by index and gaps
+// are closed up, so the slice never holds more elements than distinct
+// indices were sent. The slice stays nil when no element is present.
+func (d *Decoder) parseStructSliceField(st *decodeState, fv reflect.Value, field *fieldInfo, key, path string) error {
+	// The loop body is a closure: failing from within it would move st to
+	// the heap on every decode, so the offending index is reported after it.
+	// Keys may come in any order, the smallest offending index is reported.
+	var indices []int
+	var bad string
+	for k := range st.query.Keys() {
+		digits, ok := d.splitIndexKey(k, key)
+		if !ok {
+			continue
 		}
-		fv.SetUint(n)
-
-	case reflect.Uint8:
-		n, err := strconv.ParseUint(val, 10, 8)
-		if err != nil {
-			return strconvNumError(err, val)
+		idx, err := strconv.Atoi(digits)
+		if err != nil || idx > d.maxIndex {
+			if bad == "" || lessDigits(digits, bad) {
+				bad = digits
+			}
+			continue
 		}
-		fv.SetUint(n)
-
-	case reflect.Uint16:
-		n, err := strconv.ParseUint(val, 10, 16)
-		if err != nil {
-			return strconvNumError(err, val)
+		indices = append(indices, idx)
+	}
+	if bad != "" {
+		return st.fail(path+"["+bad+"]", fmt.Errorf("%w: index %s exceeds maximum %d", ErrOutOfRange, bad, d.maxIndex))
+	}
+	if len(indices) == 0 {
+		if field.required {
+			return st.fail(path, fmt.Errorf("%w: %q", ErrMissingValue, key+"[0]"))
 		}
-		fv.SetUint(n)
-
-	case reflect.Uint32:
-		n, err := strconv.ParseUint(val, 10, 32)
-		if err != nil {
-			return strconvNumError(err, val)
+		return nil
+	}
+	slices.Sort(indices)
+	indices = slices.Compact(indices)
+
+	slice := reflect.MakeSlice(field.typ, len(indices), len(indices))
+	isPtr := field.typ.Elem().Kind() == reflect.Ptr
+	for i, idx := range indices {
+		elem := slice.Index(i)
+		if isPtr {
+			elem.Set(reflect.New(field.structElem))
+			elem = elem.Elem()
 		}
-		fv.SetUint(n)
 
-	case reflect.Uint64:
-		n, err := strconv.ParseUint(val, 10, 64)
-		if err != nil {
-			return strconvNumError(err, val)
+		n := strconv.Itoa(idx)
+		elemPath := path + "[" + n + "]"
+		if err := d.parseStruct(st, elem, field.structElem, "", key+"["+n+"]", elemPath); err != nil {
+			if err := st.fail(elemPath, err); err != nil {
+				return err
+			}
 		}
-		fv.SetUint(n)
+	}
 
-	// ----- Floats -----
-	case reflect.Float32:
-		f, err := strconv.ParseFloat(val, 32)
-		if err != nil {
-			return strconvNumError(err, val)
+	fv.Set(slice)
+	return nil
+}
+
+// parseFileField sets a file field to the files uploaded under key
+func (d *Decoder) parseFileField(st *decodeState, fv reflect.Value, field *fieldInfo, key, path string) error {
+	headers := st.files[key]
+	if len(headers) == 0 {
+		if field.required {
+			return st.fail(path, fmt.Errorf("%w: %q", ErrMissingValue, key))
 		}
-		fv.SetFloat(f)
+		return nil
+	}
 
-	case reflect.Float64:
-		f, err := strconv.ParseFloat(val, 64)
-		if err != nil {
-			return strconvNumError(err, val)
+	st.consume(key)
+	if field.typ == fileHeaderType {
+		fv.Set(reflect.ValueOf(headers[0]))
+	} else {
+		fv.Set(reflect.ValueOf(slices.Clone(headers)))
+	}
+	return nil
+}
+
+// lessDigits reports whether the decimal a, without leading zeros like the
+// indices splitIndexKey returns, is smaller than b. Neither has to fit an int.
+func lessDigits(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// parseMapField fills a map[string]T field from every key addressing one of
+// its entries, e.g. filter[status]=active. Entries are decoded in key order
+// and the map is only allocated when at least one entry is present.
+func (d *Decoder) parseMapField(st *decodeState, fv reflect.Value, field *fieldInfo, key, path string) error {
+	var subs []string
+	for k := range st.query.Keys() {
+		if sub, ok := d.splitSubKey(k, key); ok {
+			subs = append(subs, sub)
 		}
-		fv.SetFloat(f)
+	}
+	if len(subs) == 0 {
+		if field.required {
+			return st.fail(path, fmt.Errorf("%w: %q", ErrMissingValue, d.subKey(key, "*")))
+		}
+		return nil
+	}
+	slices.Sort(subs)
+	subs = slices.Compact(subs)
 
-	default:
-		return fmt.Errorf("%w: %v", ErrUnsupportedKind, typ.Kind())
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMapWithSize(field.typ, len(subs)))
 	}
 
+	keyType, valType := field.typ.Key(), field.typ.Elem()
+	for _, sub := range subs {
+		entryKey := d.subKey(key, sub)
+		st.consume(entryKey)
+		vals, _ := st.query.Lookup(entryKey)
+		if field.nonempty && isBlank(vals) {
+			if err := st.failFrom(originQuery, entryKey, path+"["+sub+"]", fmt.Errorf("%w: %q", ErrEmptyValue, entryKey)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		elem := reflect.New(valType).Elem()
+		if err := field.mapValue.set(elem, vals); err != nil {
+			if err := st.failFrom(originQuery, entryKey, path+"["+sub+"]", err); err != nil {
+				return err
+			}
+			continue
+		}
+		fv.SetMapIndex(reflect.ValueOf(sub).Convert(keyType), elem)
+	}
 	return nil
 }
 
-// parseSliceFromStrings parses comma-separated values directly into a slice without intermediate allocations
-func parseSliceFromStrings(vals []string, sliceType reflect.Type) (reflect.Value, error) {
+// parseSliceFromStrings parses sep-delimited values directly into a slice,
+// decoding each element with set. Elements only allocate when they hold
+// escapes, see cutElem. With nosplit every value is a single element.
+func parseSliceFromStrings(vals []string, sliceType reflect.Type, set scalarSetter, sep byte, nosplit bool) (reflect.Value, error) {
 	if len(vals) == 0 {
 		return reflect.Zero(sliceType), nil
 	}
 
-	// Count total elements needed for pre-allocation
+	// Count separators + 1 for an upper bound of the number of elements,
+	// escaped separators are trimmed off once the slice is filled
 	totalElements := 0
 	for _, v := range vals {
 		if v == "" {
 			continue
 		}
-		// Count commas + 1 for number of elements
-		for i := 0; i < len(v); i++ {
-			if v[i] == ',' {
-				totalElements++
+		if !nosplit {
+			for i := 0; i < len(v); i++ {
+				if v[i] == sep {
+					totalElements++
+				}
 			}
 		}
 		totalElements++ // +1 for the string itself
@@ -270,50 +418,29 @@ func parseSliceFromStrings(vals []string, sliceType reflect.Type) (reflect.Value
 	}
 
 	slice := reflect.MakeSlice(sliceType, totalElements, totalElements)
-	elemType := sliceType.Elem()
 	elemIndex := 0
 
-	// Parse directly without creating intermediate strings
 	for _, v := range vals {
-		vLen := len(v)
-		if vLen == 0 {
-			continue
-		}
-
-		start := 0
-		for i := 0; i <= vLen; i++ {
-			if i == vLen || v[i] == ',' {
-				// Trim whitespace using indices directly
-				trimStart := start
-				trimEnd := i
-
-				// Trim leading whitespace - optimized with single comparison
-				for trimStart < trimEnd {
-					c := v[trimStart]
-					if c > ' ' && c != '\t' && c != '\n' && c != '\r' {
-						break
-					}
-					trimStart++
-				}
-
-				// Trim trailing whitespace - optimized
-				for trimStart < trimEnd {
-					c := v[trimEnd-1]
-					if c > ' ' && c != '\t' && c != '\n' && c != '\r' {
-						break
-					}
-					trimEnd--
-				}
+		for i := 0; i < len(v); {
+			var elem string
+			var escaped bool
+			if nosplit {
+				elem, i = trimElem(v), len(v)
+			} else {
+				elem, i, escaped = cutElem(v, i, sep)
+			}
 
-				// Only process non-empty trimmed parts
-				if trimStart < trimEnd {
-					if err := setSingleValue(v[trimStart:trimEnd], slice.Index(elemIndex), elemType); err != nil {
-						return reflect.Zero(sliceType), fmt.Errorf("element [%d]: %w", elemIndex, err)
-					}
-					elemIndex++
-				}
-				start = i + 1
+			// Only process non-empty trimmed parts
+			if elem == "" {
+				continue
+			}
+			if escaped {
+				elem = unescapeElem(elem, sep)
+			}
+			if err := set(slice.Index(elemIndex), elem); err != nil {
+				return reflect.Zero(sliceType), fmt.Errorf("element [%d]: %w", elemIndex, err)
 			}
+			elemIndex++
 		}
 	}
 
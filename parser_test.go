@@ -1,8 +1,10 @@
 package qparser
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
+	"reflect"
 	"testing"
 	"time"
 
@@ -740,6 +742,793 @@ func TestNestedStruct(t *testing.T) {
 	})
 }
 
+func TestDefaults(t *testing.T) {
+	type defaults struct {
+		Page    int        `qp:"page,default=1"`
+		Limit   *int       `qp:"limit,default=20"`
+		Sort    string     `qp:"sort,default=created_at"`
+		Tags    []string   `qp:"tags,default=a|b|c"`
+		IDs     *[]int     `qp:"ids,default=1|2"`
+		From    time.Time  `qp:"from,default=2025-07-01"`
+		Active  bool       `qp:"active,default=true"`
+		Comment string     `qp:"comment"`
+		Ratio   *float64   `qp:"ratio"`
+		Until   *time.Time `qp:"until,default=2025-07-31"`
+	}
+
+	t.Run("Missing", func(t *testing.T) {
+		var d defaults
+		err := Parse(url.Values{}, &d)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, d.Page)
+		assert.Equal(t, ptr(20), d.Limit)
+		assert.Equal(t, "created_at", d.Sort)
+		assert.Equal(t, []string{"a", "b", "c"}, d.Tags)
+		assert.Equal(t, &[]int{1, 2}, d.IDs)
+		assert.True(t, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC).Equal(d.From))
+		assert.True(t, d.Active)
+		assert.Equal(t, "", d.Comment)
+		assert.Nil(t, d.Ratio)
+		assert.True(t, time.Date(2025, 7, 31, 0, 0, 0, 0, time.UTC).Equal(*d.Until))
+	})
+
+	t.Run("Provided", func(t *testing.T) {
+		values, err := url.ParseQuery("page=3&limit=5&sort=name&tags=x&ids=9&active=false")
+		require.NoError(t, err)
+
+		var d defaults
+		err = Parse(values, &d)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, d.Page)
+		assert.Equal(t, ptr(5), d.Limit)
+		assert.Equal(t, "name", d.Sort)
+		assert.Equal(t, []string{"x"}, d.Tags)
+		assert.Equal(t, &[]int{9}, d.IDs)
+		assert.False(t, d.Active)
+	})
+
+	t.Run("Not-Shared", func(t *testing.T) {
+		var d1, d2 defaults
+		require.NoError(t, Parse(url.Values{}, &d1))
+		require.NoError(t, Parse(url.Values{}, &d2))
+
+		d1.Tags[0] = "mutated"
+		assert.Equal(t, "a", d2.Tags[0])
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		type child struct {
+			Limit int `qp:"limit,default=50"`
+		}
+		type parent struct {
+			C *child
+		}
+
+		var p parent
+		err := Parse(url.Values{}, &p)
+		require.NoError(t, err)
+		assert.Equal(t, 50, p.C.Limit)
+	})
+
+	t.Run("Invalid-Default", func(t *testing.T) {
+		type invalid struct {
+			Limit int `qp:"limit,default=twenty"`
+		}
+
+		var v invalid
+		err := Parse(url.Values{"limit": {"10"}}, &v)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "invalid.Limit", fieldErr.FieldName)
+
+		// Reported on every parse, not only when the cache is built.
+		err = Parse(url.Values{}, &v)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+	})
+
+	t.Run("Invalid-Option", func(t *testing.T) {
+		type invalid struct {
+			Limit int `qp:"limit,defualt=20"`
+		}
+
+		var v invalid
+		err := Parse(url.Values{}, &v)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+	})
+
+	t.Run("Option-Without-Value", func(t *testing.T) {
+		type invalid struct {
+			Limit int `qp:"limit,default"`
+		}
+
+		var v invalid
+		err := Parse(url.Values{}, &v)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+	})
+}
+
+func TestRequired(t *testing.T) {
+	type child struct {
+		Token *string `qp:"token,required"`
+	}
+
+	type required struct {
+		ID      int     `qp:"id,required"`
+		Name    *string `qp:"name,required,nonempty"`
+		Comment string  `qp:"comment,nonempty"`
+		Tags    []int   `qp:"tags,required"`
+		C       *child
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		values, err := url.ParseQuery("id=1&name=foo&tags=1,2&token=abc")
+		require.NoError(t, err)
+
+		var r required
+		err = Parse(values, &r)
+		require.NoError(t, err)
+		assert.Equal(t, 1, r.ID)
+		assert.Equal(t, ptr("foo"), r.Name)
+		assert.Equal(t, []int{1, 2}, r.Tags)
+		assert.Equal(t, ptr("abc"), r.C.Token)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		values, err := url.ParseQuery("name=foo&tags=1&token=abc")
+		require.NoError(t, err)
+
+		var r required
+		err = Parse(values, &r)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrMissingValue)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "required.ID", fieldErr.FieldName)
+	})
+
+	t.Run("Present-But-Empty", func(t *testing.T) {
+		// required alone accepts an empty value, it only checks presence.
+		values, err := url.ParseQuery("id=1&name=foo&tags=&token=")
+		require.NoError(t, err)
+
+		var r required
+		err = Parse(values, &r)
+		require.NoError(t, err)
+		assert.Nil(t, r.Tags)
+		assert.Nil(t, r.C.Token)
+
+		// required,nonempty rejects it.
+		values, err = url.ParseQuery("id=1&name=%20&tags=1&token=abc")
+		require.NoError(t, err)
+
+		err = Parse(values, &r)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrEmptyValue)
+		assert.NotErrorIs(t, err, ErrMissingValue)
+	})
+
+	t.Run("Nonempty-Only", func(t *testing.T) {
+		values, err := url.ParseQuery("id=1&name=foo&tags=1&token=abc&comment=&comment=")
+		require.NoError(t, err)
+
+		var r required
+		err = Parse(values, &r)
+		assert.ErrorIs(t, err, ErrEmptyValue)
+	})
+
+	t.Run("Nested-Pointer", func(t *testing.T) {
+		values, err := url.ParseQuery("id=1&name=foo&tags=1")
+		require.NoError(t, err)
+
+		var r required
+		err = Parse(values, &r)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrMissingValue)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "required.C.Token", fieldErr.FieldName)
+	})
+
+	t.Run("Required-With-Default", func(t *testing.T) {
+		type invalid struct {
+			ID int `qp:"id,required,default=1"`
+		}
+
+		var v invalid
+		err := Parse(url.Values{"id": {"1"}}, &v)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+	})
+}
+
+func TestParseAll(t *testing.T) {
+	type child struct {
+		Limit int `qp:"limit"`
+		Token int `qp:"token,required"`
+	}
+
+	type params struct {
+		Page   int    `qp:"page"`
+		Active bool   `qp:"active"`
+		Name   string `qp:"name"`
+		IDs    []int8 `qp:"ids"`
+		C      *child
+		Ratio  float32 `qp:"ratio"`
+	}
+
+	values, err := url.ParseQuery("page=x&active=maybe&name=ok&ids=1,300&limit=y&ratio=0.5")
+	require.NoError(t, err)
+
+	t.Run("Collects", func(t *testing.T) {
+		var p params
+		err := ParseAll(values, &p)
+		require.Error(t, err)
+
+		var errs FieldErrors
+		require.ErrorAs(t, err, &errs)
+
+		names := make([]string, len(errs))
+		for i, fe := range errs {
+			names[i] = fe.FieldName
+		}
+		assert.Equal(t, []string{
+			"params.Page",
+			"params.Active",
+			"params.IDs",
+			"params.C.Limit",
+			"params.C.Token",
+		}, names)
+
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.ErrorIs(t, err, ErrOutOfRange)
+		assert.ErrorIs(t, err, ErrMissingValue)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "params.Page", fieldErr.FieldName)
+
+		// Valid fields are still decoded.
+		assert.Equal(t, "ok", p.Name)
+		assert.Equal(t, float32(0.5), p.Ratio)
+	})
+
+	t.Run("Fail-Fast", func(t *testing.T) {
+		var p params
+		err := Parse(values, &p)
+		require.Error(t, err)
+
+		var errs FieldErrors
+		assert.False(t, errors.As(err, &errs))
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "params.Page", fieldErr.FieldName)
+	})
+
+	t.Run("Decoder-Option", func(t *testing.T) {
+		var p params
+		err := NewDecoder(WithAllErrors()).Parse(values, &p)
+
+		var errs FieldErrors
+		require.ErrorAs(t, err, &errs)
+		assert.Len(t, errs, 5)
+		assert.Contains(t, err.Error(), "5 errors: ")
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		var p params
+		err := ParseAll(url.Values{"token": {"1"}}, &p)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Nested-Unexported", func(t *testing.T) {
+		type child struct {
+			f1 string `qp:"f1"` //nolint
+		}
+		type parent struct {
+			Page int `qp:"page"`
+			C    child
+		}
+
+		var p parent
+		err := ParseAll(url.Values{"page": {"x"}}, &p)
+
+		var errs FieldErrors
+		require.ErrorAs(t, err, &errs)
+		require.Len(t, errs, 2)
+		assert.Equal(t, "parent.C", errs[1].FieldName)
+		assert.ErrorIs(t, err, ErrUnexportedStruct)
+	})
+}
+
+func TestMaps(t *testing.T) {
+	type filterKey string
+
+	type maps struct {
+		Filter map[string]string       `qp:"filter"`
+		Range  map[string]int          `qp:"range"`
+		In     map[filterKey][]int     `qp:"in"`
+		Since  map[string]*time.Time   `qp:"since"`
+		Status map[string]status       `qp:"status"`
+		Empty  map[string]string       `qp:"empty"`
+		Deep   map[string]map[int]bool `qp:"deep"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		values, err := url.ParseQuery(
+			"filter[status]=active&filter[owner]=42&range[min]=1&range[max]=10&in[ids]=1,2&in[ids]=3" +
+				"&since[created]=2025-07-01&status[a]=archived&filter=ignored&filter[]=ignored&filter[a][b]=ignored",
+		)
+		require.NoError(t, err)
+
+		var m maps
+		err = Parse(values, &m)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{"status": "active", "owner": "42"}, m.Filter)
+		assert.Equal(t, map[string]int{"min": 1, "max": 10}, m.Range)
+		assert.Equal(t, map[filterKey][]int{"ids": {1, 2, 3}}, m.In)
+		assert.True(t, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC).Equal(*m.Since["created"]))
+		assert.Equal(t, map[string]status{"a": statusArchived}, m.Status)
+		assert.Nil(t, m.Empty)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		values, err := url.ParseQuery("range[min]=1&range[max]=ten")
+		require.NoError(t, err)
+
+		var m maps
+		err = Parse(values, &m)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "maps.Range[max]", fieldErr.FieldName)
+	})
+
+	t.Run("Collect", func(t *testing.T) {
+		values, err := url.ParseQuery("range[b]=x&range[a]=y&range[c]=3")
+		require.NoError(t, err)
+
+		var m maps
+		err = ParseAll(values, &m)
+
+		var errs FieldErrors
+		require.ErrorAs(t, err, &errs)
+		require.Len(t, errs, 2)
+		assert.Equal(t, "maps.Range[a]", errs[0].FieldName)
+		assert.Equal(t, "maps.Range[b]", errs[1].FieldName)
+		assert.Equal(t, map[string]int{"c": 3}, m.Range)
+	})
+
+	t.Run("Unsupported-Value", func(t *testing.T) {
+		var m maps
+		err := Parse(url.Values{"deep[a]": {"true"}}, &m)
+		assert.ErrorIs(t, err, ErrUnsupportedKind)
+	})
+
+	t.Run("Required", func(t *testing.T) {
+		type required struct {
+			Filter map[string]string `qp:"filter,required,nonempty"`
+		}
+
+		var r required
+		err := Parse(url.Values{"filter": {"x"}}, &r)
+		assert.ErrorIs(t, err, ErrMissingValue)
+
+		err = Parse(url.Values{"filter[a]": {""}}, &r)
+		assert.ErrorIs(t, err, ErrEmptyValue)
+	})
+
+	t.Run("Dot-Notation", func(t *testing.T) {
+		values, err := url.ParseQuery("filter.status=active&filter.owner.name=bob&filter[x]=ignored&range.min=5")
+		require.NoError(t, err)
+
+		var m maps
+		err = NewDecoder(WithNotation(DotNotation)).Parse(values, &m)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"status": "active", "owner.name": "bob"}, m.Filter)
+		assert.Equal(t, map[string]int{"min": 5}, m.Range)
+	})
+
+	t.Run("Encode", func(t *testing.T) {
+		src := maps{
+			Filter: map[string]string{"status": "active", "owner": "42"},
+			In:     map[filterKey][]int{"ids": {1, 2}},
+		}
+
+		values, err := Encode(src)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"filter[owner]":  {"42"},
+			"filter[status]": {"active"},
+			"in[ids]":        {"1", "2"},
+		}, values)
+
+		var got maps
+		require.NoError(t, Parse(values, &got))
+		assert.Equal(t, src, got)
+
+		values, err = NewEncoder(WithNotation(DotNotation)).Encode(src)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"active"}, values["filter.status"])
+	})
+}
+
+func TestPrefixedNestedStruct(t *testing.T) {
+	type geo struct {
+		Lat float64 `qp:"lat"`
+		Lng float64 `qp:"lng"`
+	}
+
+	type address struct {
+		City  string            `qp:"city"`
+		Zip   *int              `qp:"zip"`
+		Geo   *geo              `qp:"geo"`
+		Extra map[string]string `qp:"extra"`
+	}
+
+	type order struct {
+		ID       int      `qp:"id"`
+		Billing  address  `qp:"billing"`
+		Shipping *address `qp:"shipping"`
+		Default  address
+	}
+
+	t.Run("Bracket", func(t *testing.T) {
+		values, err := url.ParseQuery(
+			"id=1&billing[city]=Paris&billing[zip]=75001&billing[geo][lat]=48.85&billing[extra][floor]=2" +
+				"&shipping[city]=Lyon&city=Nice&billing.city=ignored",
+		)
+		require.NoError(t, err)
+
+		var o order
+		err = Parse(values, &o)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, o.ID)
+		assert.Equal(t, address{
+			City:  "Paris",
+			Zip:   ptr(75001),
+			Geo:   &geo{Lat: 48.85},
+			Extra: map[string]string{"floor": "2"},
+		}, o.Billing)
+		assert.Equal(t, &address{City: "Lyon", Geo: &geo{}}, o.Shipping)
+		assert.Equal(t, address{City: "Nice", Geo: &geo{}}, o.Default)
+	})
+
+	t.Run("Dot", func(t *testing.T) {
+		values, err := url.ParseQuery("billing.city=Paris&billing.geo.lng=2.35&billing.extra.floor=2&shipping[city]=ignored")
+		require.NoError(t, err)
+
+		var o order
+		err = NewDecoder(WithNotation(DotNotation)).Parse(values, &o)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Paris", o.Billing.City)
+		assert.Equal(t, 2.35, o.Billing.Geo.Lng)
+		assert.Equal(t, map[string]string{"floor": "2"}, o.Billing.Extra)
+		assert.Equal(t, "", o.Shipping.City)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var o order
+		err := Parse(url.Values{"shipping[geo][lat]": {"north"}}, &o)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "order.Shipping.Geo.Lat", fieldErr.FieldName)
+	})
+
+	t.Run("Options-Rejected", func(t *testing.T) {
+		type invalid struct {
+			Billing address `qp:"billing,required"`
+		}
+
+		var v invalid
+		err := Parse(url.Values{}, &v)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+	})
+
+	t.Run("Encode", func(t *testing.T) {
+		src := order{
+			ID:       7,
+			Billing:  address{City: "Paris", Geo: &geo{Lat: 1}},
+			Shipping: &address{City: "Lyon", Zip: ptr(69001), Geo: &geo{}},
+			Default:  address{City: "Nice", Geo: &geo{}},
+		}
+
+		values, err := Encode(src)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Paris"}, values["billing[city]"])
+		assert.Equal(t, []string{"1"}, values["billing[geo][lat]"])
+		assert.Equal(t, []string{"69001"}, values["shipping[zip]"])
+		assert.Equal(t, []string{"Nice"}, values["city"])
+
+		var got order
+		require.NoError(t, Parse(values, &got))
+		assert.Equal(t, src, got)
+	})
+}
+
+func TestStructSlices(t *testing.T) {
+	type attr struct {
+		Key string `qp:"key"`
+	}
+
+	type item struct {
+		Name  string            `qp:"name,required"`
+		Qty   int               `qp:"qty,default=1"`
+		Tags  []string          `qp:"tags"`
+		Attrs []attr            `qp:"attrs"`
+		Meta  map[string]string `qp:"meta"`
+		Dim   struct {
+			W int `qp:"w"`
+		} `qp:"dim"`
+	}
+
+	type order struct {
+		ID    int     `qp:"id"`
+		Items []item  `qp:"items"`
+		Refs  []*attr `qp:"refs"`
+		Ship  struct {
+			Lines []attr `qp:"lines"`
+		} `qp:"ship"`
+	}
+
+	t.Run("Bracket", func(t *testing.T) {
+		values, err := url.ParseQuery(
+			"id=9&items[0][name]=a&items[0][qty]=2&items[0][tags]=x,y&items[0][attrs][0][key]=k0" +
+				"&items[0][meta][color]=red&items[0][dim][w]=3&items[5][name]=b&refs[1][key]=r1" +
+				"&ship[lines][0][key]=l0&items[x][name]=ignored&items[01][name]=ignored&items[2]=ignored",
+		)
+		require.NoError(t, err)
+
+		var o order
+		err = Parse(values, &o)
+		require.NoError(t, err)
+
+		first := item{
+			Name:  "a",
+			Qty:   2,
+			Tags:  []string{"x", "y"},
+			Attrs: []attr{{Key: "k0"}},
+			Meta:  map[string]string{"color": "red"},
+		}
+		first.Dim.W = 3
+
+		assert.Equal(t, 9, o.ID)
+		assert.Equal(t, []item{first, {Name: "b", Qty: 1}}, o.Items)
+		assert.Equal(t, []*attr{{Key: "r1"}}, o.Refs)
+		assert.Equal(t, []attr{{Key: "l0"}}, o.Ship.Lines)
+	})
+
+	t.Run("Dot", func(t *testing.T) {
+		values, err := url.ParseQuery("items[0].name=a&items[0].dim.w=4&items[0].attrs[1].key=k1&items[1][name]=ignored")
+		require.NoError(t, err)
+
+		var o order
+		err = NewDecoder(WithNotation(DotNotation)).Parse(values, &o)
+		require.NoError(t, err)
+
+		require.Len(t, o.Items, 1)
+		assert.Equal(t, "a", o.Items[0].Name)
+		assert.Equal(t, 4, o.Items[0].Dim.W)
+		assert.Equal(t, []attr{{Key: "k1"}}, o.Items[0].Attrs)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var o order
+		require.NoError(t, Parse(url.Values{"items": {"a"}}, &o))
+		assert.Nil(t, o.Items)
+		assert.Nil(t, o.Refs)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		values, err := url.ParseQuery("items[0][name]=a&items[1][name]=b&items[1][qty]=many&items[3][qty]=2")
+		require.NoError(t, err)
+
+		var o order
+		err = ParseAll(values, &o)
+
+		var errs FieldErrors
+		require.ErrorAs(t, err, &errs)
+		require.Len(t, errs, 2)
+		assert.Equal(t, "order.Items[1].Qty", errs[0].FieldName)
+		assert.ErrorIs(t, errs[0], ErrInvalidValue)
+		assert.Equal(t, "order.Items[3].Name", errs[1].FieldName)
+		assert.ErrorIs(t, errs[1], ErrMissingValue)
+	})
+
+	t.Run("Max-Index", func(t *testing.T) {
+		var o order
+		err := NewDecoder(WithMaxSliceIndex(10)).Parse(url.Values{"items[11][name]": {"a"}}, &o)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrOutOfRange)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "order.Items[11]", fieldErr.FieldName)
+
+		err = Parse(url.Values{"items[99999999999999999999][name]": {"a"}}, &o)
+		assert.ErrorIs(t, err, ErrOutOfRange)
+
+		// Map iteration order must not change the reported index
+		values := url.Values{
+			"items[99999999999999999999][name]": {"a"},
+			"items[12][name]":                   {"b"},
+			"items[2][name]":                    {"c"},
+			"items[100][name]":                  {"d"},
+		}
+		for range 20 {
+			err := NewDecoder(WithMaxSliceIndex(10)).Parse(values, &o)
+			require.ErrorAs(t, err, &fieldErr)
+			assert.Equal(t, "order.Items[12]", fieldErr.FieldName)
+		}
+	})
+
+	t.Run("Encode", func(t *testing.T) {
+		src := order{
+			ID: 1,
+			Items: []item{
+				{Name: "a", Qty: 2, Attrs: []attr{{Key: "k"}}},
+				{Name: "b", Qty: 1, Meta: map[string]string{"m": "v"}},
+			},
+			Refs: []*attr{{Key: "r"}},
+		}
+
+		values, err := Encode(src)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, values["items[0][name]"])
+		assert.Equal(t, []string{"k"}, values["items[0][attrs][0][key]"])
+		assert.Equal(t, []string{"v"}, values["items[1][meta][m]"])
+		assert.Equal(t, []string{"r"}, values["refs[0][key]"])
+
+		var got order
+		require.NoError(t, Parse(values, &got))
+		assert.Equal(t, src, got)
+
+		values, err = NewEncoder(WithNotation(DotNotation)).Encode(src)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"k"}, values["items[0].attrs[0].key"])
+	})
+}
+
+func TestRemain(t *testing.T) {
+	type item struct {
+		Name string `qp:"name"`
+	}
+
+	type search struct {
+		Query  string            `qp:"q"`
+		Page   int               `qp:"page,default=1"`
+		Filter map[string]string `qp:"filter"`
+		Items  []item            `qp:"items"`
+		Nested struct {
+			Sort string `qp:"sort"`
+		} `qp:"opts"`
+		Rest url.Values `qp:",remain"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		values, err := url.ParseQuery(
+			"q=go&filter[lang]=en&items[0][name]=a&opts[sort]=asc" +
+				"&facet=color&facet=size&items[0][qty]=2&opts=x&utm_source=mail",
+		)
+		require.NoError(t, err)
+
+		var s search
+		require.NoError(t, Parse(values, &s))
+
+		assert.Equal(t, "go", s.Query)
+		assert.Equal(t, 1, s.Page)
+		assert.Equal(t, "asc", s.Nested.Sort)
+		assert.Equal(t, url.Values{
+			"facet":         {"color", "size"},
+			"items[0][qty]": {"2"},
+			"opts":          {"x"},
+			"utm_source":    {"mail"},
+		}, s.Rest)
+	})
+
+	t.Run("Nothing-Left", func(t *testing.T) {
+		var s search
+		require.NoError(t, Parse(url.Values{"q": {"go"}}, &s))
+		assert.Nil(t, s.Rest)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		type inner struct {
+			Extra map[string][]string `qp:",remain"`
+		}
+		type outer struct {
+			Query string `qp:"q"`
+			In    *inner
+		}
+
+		var o outer
+		require.NoError(t, Parse(url.Values{"q": {"go"}, "x": {"1"}}, &o))
+		assert.Equal(t, map[string][]string{"x": {"1"}}, o.In.Extra)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		var s search
+		err := NewDecoder(WithStrict()).Parse(url.Values{"q": {"go"}, "facet": {"color"}}, &s)
+		require.NoError(t, err, "captured keys are not unknown")
+		assert.Equal(t, url.Values{"facet": {"color"}}, s.Rest)
+	})
+
+	t.Run("Invalid-Tag", func(t *testing.T) {
+		type withKey struct {
+			Rest url.Values `qp:"rest,remain"`
+		}
+		type withOption struct {
+			Rest url.Values `qp:",remain,required"`
+		}
+		type wrongType struct {
+			Rest map[string]string `qp:",remain"`
+		}
+
+		for _, dst := range []any{&withKey{}, &withOption{}, &wrongType{}} {
+			err := Parse(url.Values{}, dst)
+			assert.ErrorIs(t, err, ErrInvalidTag)
+		}
+	})
+}
+
+func TestCompilePlan(t *testing.T) {
+	type inner struct {
+		Sort string `qp:"sort"`
+	}
+
+	type item struct {
+		Name string `qp:"name"`
+	}
+
+	type params struct {
+		Page   int `qp:"page"`
+		Paging struct {
+			Limit int `qp:"limit"`
+			Inner *inner
+		} `qp:"paging"`
+		Items []item          `qp:"items"`
+		Tags  map[string]bool `qp:"tags"`
+	}
+
+	d := NewDecoder()
+	info := d.getStructCache(reflect.TypeFor[params](), "")
+
+	type summary struct {
+		kind  stepKind
+		path  string
+		key   string
+		index []int
+	}
+	var got []summary
+	for _, step := range info.steps {
+		got = append(got, summary{step.kind, step.path, step.field.key, step.index})
+	}
+
+	assert.Equal(t, []summary{
+		{stepLeaf, "Page", "page", []int{0}},
+		{stepLeaf, "Paging.Limit", "paging[limit]", []int{1, 0}},
+		{stepAlloc, "Paging.Inner", "paging", []int{1, 1}},
+		{stepLeaf, "Paging.Inner.Sort", "paging[sort]", []int{1, 1, 0}},
+		{stepStructSlice, "Items", "items", []int{2}},
+		{stepMap, "Tags", "tags", []int{3}},
+	}, got)
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
@@ -0,0 +1,281 @@
+package qparser
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// stepKind selects what a planStep does with its field
+type stepKind uint8
+
+const (
+	stepLeaf        stepKind = iota // decode the values of one key
+	stepAlloc                       // allocate a nil pointer to a nested struct
+	stepMap                         // decode the entries of a map field
+	stepStructSlice                 // decode the indexed elements of a struct slice
+	stepRemain                      // remember a remain field, filled at the end
+	stepFile                        // set the files uploaded under one key
+	stepFail                        // report a nested struct that cannot be decoded
+	stepBefore                      // call BeforeParse on a struct, see BeforeParser
+	stepAfter                       // call AfterParse on a struct, see AfterParser
+)
+
+// planStep is one instruction of the decode plan of a struct. Nested structs
+// are flattened into the plan of their parent, so index and path are relative
+// to the struct the plan was compiled for rather than to the field's parent.
+type planStep struct {
+	kind  stepKind
+	field *fieldInfo
+	index []int  // index sequence reaching the field from the plan's struct, empty for itself
+	path  string // dotted field path from the plan's struct, e.g. Pagination.Page, empty for itself
+	err   error  // error reported by stepFail
+}
+
+// setter decodes every raw value of a key into fv
+type setter func(fv reflect.Value, vals []string) error
+
+// scalarSetter decodes a single raw value into fv
+type scalarSetter func(fv reflect.Value, val string) error
+
+// compilePlan flattens the fields of info, the metadata of rt, and recursively
+// those of its nested structs, into a single list of steps. Struct slice
+// elements are not inlined, their own plan is looked up per element. The
+// hooks of rt surround the steps of its fields.
+func (d *Decoder) compilePlan(rt reflect.Type, info *structInfo) []planStep {
+	var steps []planStep
+	before, after := structHooks(rt, info)
+	if before {
+		steps = append(steps, planStep{kind: stepBefore})
+	}
+	for i := range info.fields {
+		field := &info.fields[i]
+		step := planStep{field: field, index: field.index, path: field.name}
+
+		switch {
+		case field.isNested:
+			ft := field.typ
+			if ft.Kind() == reflect.Ptr {
+				step.kind = stepAlloc
+				steps = append(steps, step)
+				ft = ft.Elem()
+			}
+
+			child := d.getStructCache(ft, field.key)
+			switch {
+			case child.hasUnexportedWithTag:
+				step.kind, step.err = stepFail, ErrUnexportedStruct
+				steps = append(steps, step)
+			case child.err != nil:
+				step.kind, step.err = stepFail, child.err
+				steps = append(steps, step)
+			default:
+				for _, cs := range child.steps {
+					cs.index = slices.Concat(field.index, cs.index)
+					cs.path = joinPath(field.name, cs.path)
+					steps = append(steps, cs)
+				}
+			}
+			continue
+		case field.remain:
+			step.kind = stepRemain
+		case field.file:
+			step.kind = stepFile
+		case field.mapValue != nil:
+			step.kind = stepMap
+		case field.structElem != nil:
+			step.kind = stepStructSlice
+		default:
+			step.kind = stepLeaf
+		}
+		steps = append(steps, step)
+	}
+	if after {
+		steps = append(steps, planStep{kind: stepAfter})
+	}
+	return steps
+}
+
+// compileSetter returns the setter of a field, resolving once the kind,
+// pointer and slice handling of its type, and its duplicate policy.
+func (d *Decoder) compileSetter(field *fieldInfo) setter {
+	set := d.compileTypeSetter(field)
+	if field.dup != DuplicateFirst && takesOneValue(field) {
+		set = withDuplicates(set, field.dup, d.separator)
+	}
+	return set
+}
+
+// compileTypeSetter is compileSetter for the type of field alone, taking the
+// first value when the type decodes a single one
+func (d *Decoder) compileTypeSetter(field *fieldInfo) setter {
+	ft := field.typ
+	if field.unmarshal != unmarshalNone {
+		kind, enum := field.unmarshal, field.enum
+		if enum != nil {
+			return func(fv reflect.Value, vals []string) error {
+				vals, err := canonicalEnum(enum, vals)
+				if err != nil {
+					return err
+				}
+				return setUnmarshalerField(fv, ft, kind, vals)
+			}
+		}
+		return func(fv reflect.Value, vals []string) error {
+			return setUnmarshalerField(fv, ft, kind, vals)
+		}
+	}
+
+	switch ft.Kind() {
+	case reflect.Ptr:
+		elemType := ft.Elem()
+		if elemType.Kind() == reflect.Slice {
+			return d.compileSliceSetter(field, elemType, true)
+		}
+		set := d.compileScalar(elemType, unmarshalNone, field.enum)
+		return func(fv reflect.Value, vals []string) error {
+			if len(vals) == 0 || vals[0] == "" {
+				return nil
+			}
+			elemVal := reflect.New(elemType)
+			if err := set(elemVal.Elem(), vals[0]); err != nil {
+				return err
+			}
+			fv.Set(elemVal)
+			return nil
+		}
+	case reflect.Slice:
+		return d.compileSliceSetter(field, ft, false)
+	default:
+		set := d.compileScalar(ft, unmarshalNone, field.enum)
+		return func(fv reflect.Value, vals []string) error {
+			if len(vals) == 0 {
+				return nil
+			}
+			return set(fv, vals[0])
+		}
+	}
+}
+
+// compileSliceSetter returns the setter of field, a []T field, or a *[]T
+// field when ptr is set, sliceType being []T
+func (d *Decoder) compileSliceSetter(field *fieldInfo, sliceType reflect.Type, ptr bool) setter {
+	elem := d.compileScalar(sliceType.Elem(), field.elemUnmarshal, field.enum)
+	sep, nosplit := field.sep, field.nosplit
+	return func(fv reflect.Value, vals []string) error {
+		slice, err := parseSliceFromStrings(vals, sliceType, elem, sep, nosplit)
+		if err != nil {
+			return err
+		}
+		if slice.Len() == 0 {
+			return nil
+		}
+		if ptr {
+			p := reflect.New(sliceType)
+			p.Elem().Set(slice)
+			slice = p
+		}
+		fv.Set(slice)
+		return nil
+	}
+}
+
+// compileScalar returns the scalarSetter of typ. um is the cached decoding
+// interface of typ, or of its element when typ is a pointer, and enum the
+// values it accepts, nil if any. Unsupported kinds only fail when a value is
+// actually decoded.
+func (d *Decoder) compileScalar(typ reflect.Type, um unmarshalKind, enum *enumSet) scalarSetter {
+	if enum != nil && typ.Kind() != reflect.Ptr {
+		return d.compileEnum(typ, um, enum)
+	}
+	if set := d.converter(typ); set != nil {
+		return set
+	}
+	if um != unmarshalNone && typ.Kind() != reflect.Ptr {
+		return func(fv reflect.Value, val string) error {
+			return unmarshal(um, fv, []string{val})
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		elemType := typ.Elem()
+		set := d.compileScalar(elemType, um, enum)
+		return func(fv reflect.Value, val string) error {
+			elemVal := reflect.New(elemType)
+			if err := set(elemVal.Elem(), val); err != nil {
+				return err
+			}
+			fv.Set(elemVal)
+			return nil
+		}
+
+	case reflect.Struct:
+		if typ != timeType {
+			break
+		}
+		return func(fv reflect.Value, val string) error {
+			t, err := d.parseTime(val)
+			if err != nil {
+				return err
+			}
+			fv.Set(reflect.ValueOf(t))
+			return nil
+		}
+
+	case reflect.String:
+		return func(fv reflect.Value, val string) error {
+			fv.SetString(val)
+			return nil
+		}
+
+	case reflect.Bool:
+		return func(fv reflect.Value, val string) error {
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return ErrInvalidValue
+			}
+			fv.SetBool(b)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := typ.Bits()
+		return func(fv reflect.Value, val string) error {
+			n, err := strconv.ParseInt(val, 10, bits)
+			if err != nil {
+				return strconvNumError(err, val)
+			}
+			fv.SetInt(n)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bits := typ.Bits()
+		return func(fv reflect.Value, val string) error {
+			n, err := strconv.ParseUint(val, 10, bits)
+			if err != nil {
+				return strconvNumError(err, val)
+			}
+			fv.SetUint(n)
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		bits := typ.Bits()
+		return func(fv reflect.Value, val string) error {
+			f, err := strconv.ParseFloat(val, bits)
+			if err != nil {
+				return strconvNumError(err, val)
+			}
+			fv.SetFloat(f)
+			return nil
+		}
+	}
+
+	err := fmt.Errorf("%w: %v", ErrUnsupportedKind, typ.Kind())
+	return func(reflect.Value, string) error {
+		return err
+	}
+}
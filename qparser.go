@@ -4,11 +4,12 @@
 // supporting nested structs, slices, pointer fields, numeric types, booleans,
 // strings, and time.Time with multiple timestamp formats.
 // The Parse, ParseRequest, and ParseURL functions all decode query parameters
-// into a struct value provided by the caller.
+// into a struct value provided by the caller using a default Decoder. Use
+// NewDecoder to obtain a Decoder with its own configuration and cache.
 package qparser
 
 import (
-	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -24,22 +25,108 @@ import (
 //	var f Filter
 //	err := qparser.Parse(url.Values{"age": {"30"}}, &f)
 func Parse(values url.Values, dst any) error {
-	rv := reflect.ValueOf(dst)
-	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
-		return errors.New("dst must be a pointer to struct")
-	}
-	rv = rv.Elem()
-	rt := rv.Type()
-	return parseStruct(values, rv, rt)
+	return defaultDecoder.Parse(values, dst)
+}
+
+// ParseAll behaves like Parse but does not stop at the first invalid field.
+// Every field failure is reported in the returned FieldErrors, each carrying
+// the full dotted path of its field.
+//
+// Example:
+//
+//	err := qparser.ParseAll(values, &f)
+//	var errs qparser.FieldErrors
+//	if errors.As(err, &errs) {
+//		for _, fe := range errs {
+//			log.Printf("%s: %v", fe.FieldName, fe.Err)
+//		}
+//	}
+func ParseAll(values url.Values, dst any) error {
+	return defaultDecoder.parse(ValuesSource(values), dst, true)
 }
 
 // ParseRequest extracts the query parameters from an http.Request and
 // decodes them into the struct pointed to by dst.
 //
-// Equivalent to calling Parse(r.URL.Query(), dst).
+// Equivalent to calling ParseQueryString(r.URL.RawQuery, dst): the result is
+// the one of Parse(r.URL.Query(), dst), except that a query url.ParseQuery
+// reports an error for, such as one using ';' separators, is rejected.
 func ParseRequest(r *http.Request, dst any) error {
-	query := r.URL.Query()
-	return Parse(query, dst)
+	return defaultDecoder.ParseRequest(r, dst)
+}
+
+// ParseForm decodes the body of a url-encoded or multipart form request into
+// the struct pointed to by dst using the default Decoder. Only r.PostForm is
+// read, query parameters are left to ParseRequest.
+//
+// The body is parsed unless the caller already did, and is limited to
+// DefaultMaxFormSize bytes; larger bodies fail with an *http.MaxBytesError.
+// Fields of type *multipart.FileHeader and []*multipart.FileHeader receive
+// the files uploaded under their key.
+//
+// Example:
+//
+//	type Upload struct {
+//		Title  string                  `qp:"title,required"`
+//		Avatar *multipart.FileHeader   `qp:"avatar"`
+//		Photos []*multipart.FileHeader `qp:"photos"`
+//	}
+//
+//	var u Upload
+//	err := qparser.ParseForm(r, &u)
+func ParseForm(r *http.Request, dst any) error {
+	return defaultDecoder.ParseForm(r, dst)
+}
+
+// Bind decodes the query parameters, path values (see http.Request.PathValue),
+// headers and cookies of r into the struct pointed to by dst using the default
+// Decoder. Fields bound outside the query use the path, header and cookie
+// tags, and every other entry point leaves them untouched.
+//
+// Example:
+//
+//	type GetInvoice struct {
+//		ID      int64    `path:"id"`
+//		Tenant  string   `header:"X-Tenant,required"`
+//		Session string   `cookie:"session"`
+//		Expand  []string `qp:"expand"`
+//	}
+//
+//	var req GetInvoice
+//	err := qparser.Bind(r, &req)
+func Bind(r *http.Request, dst any) error {
+	return defaultDecoder.Bind(r, dst)
+}
+
+// ParseSource decodes the values src holds into the struct pointed to by dst
+// using the default Decoder. It applies the conversion rules of Parse to any
+// Source, such as ValuesSource, HeaderSource, EnvSource or a Merge of them.
+//
+// Example:
+//
+//	var cfg struct {
+//		Port  int      `qp:"PORT,default=8080"`
+//		Debug bool     `qp:"DEBUG"`
+//		Hosts []string `qp:"HOSTS"`
+//	}
+//	err := qparser.ParseSource(qparser.EnvSource("APP_"), &cfg)
+func ParseSource(src Source, dst any) error {
+	return defaultDecoder.ParseSource(src, dst)
+}
+
+// ParseQueryString decodes a raw query string, as found in url.URL.RawQuery,
+// into the struct pointed to by dst using the default Decoder.
+//
+// It gives the same result as parsing raw with url.ParseQuery and passing the
+// values to Parse, including the errors url.ParseQuery reports, without
+// building the intermediate url.Values.
+//
+// Example:
+//
+//	var f Filter
+//	err := qparser.ParseQueryString("age=30&name=John%20Doe", &f)
+func ParseQueryString(raw string, dst any) error {
+	return defaultDecoder.ParseQueryString(raw, dst)
 }
 
 // ParseURL parses the query parameters from the provided URL string and
@@ -47,10 +134,64 @@ func ParseRequest(r *http.Request, dst any) error {
 //
 // Returns an error if the URL cannot be parsed.
 func ParseURL(addr string, dst any) error {
-	urlObj, err := url.Parse(addr)
+	return defaultDecoder.ParseURL(addr, dst)
+}
+
+// ParseAs decodes values into a new T using the default Decoder and returns it.
+//
+// T must be a struct or a pointer to a struct, in which case the struct is
+// allocated. On error the zero T is returned along with the error.
+//
+// Example:
+//
+//	f, err := qparser.ParseAs[Filter](r.URL.Query())
+func ParseAs[T any](values url.Values) (T, error) {
+	return parseAs[T](func(dst any) error {
+		return defaultDecoder.Parse(values, dst)
+	})
+}
+
+// ParseRequestAs decodes the query parameters of r into a new T like
+// ParseRequest, see ParseAs.
+func ParseRequestAs[T any](r *http.Request) (T, error) {
+	return parseAs[T](func(dst any) error {
+		return defaultDecoder.ParseRequest(r, dst)
+	})
+}
+
+// ParseURLAs decodes the query parameters of the URL string addr into a new T
+// like ParseURL, see ParseAs. Returns an error if the URL cannot be parsed.
+func ParseURLAs[T any](addr string) (T, error) {
+	return parseAs[T](func(dst any) error {
+		return defaultDecoder.ParseURL(addr, dst)
+	})
+}
+
+// parseAs decodes into a new T with parse, returning the zero T on error
+func parseAs[T any](parse func(dst any) error) (T, error) {
+	var v T
+	dst, err := newTarget(&v)
 	if err != nil {
-		return err
+		return v, err
+	}
+	if err := parse(dst); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+// newTarget returns the pointer to struct to decode into for *v. When T is
+// itself a pointer to struct, *v is set to a newly allocated struct.
+func newTarget[T any](v *T) (any, error) {
+	rt := reflect.TypeFor[T]()
+	switch {
+	case rt.Kind() == reflect.Struct:
+		return v, nil
+	case rt.Kind() == reflect.Ptr && rt.Elem().Kind() == reflect.Struct:
+		ptr := reflect.New(rt.Elem())
+		reflect.ValueOf(v).Elem().Set(ptr)
+		return ptr.Interface(), nil
 	}
-	queryValues := urlObj.Query()
-	return Parse(queryValues, dst)
+	return nil, fmt.Errorf("T must be a struct or pointer to struct, got %v", rt)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -102,3 +103,49 @@ func TestParseURL(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestParseAs(t *testing.T) {
+	values := url.Values{"page": {"2"}, "limit": {"20"}}
+
+	t.Run("Struct", func(t *testing.T) {
+		got, err := ParseAs[Pagination](values)
+		require.NoError(t, err)
+		assert.Equal(t, Pagination{Page: 2, Limit: 20}, got)
+	})
+
+	t.Run("Pointer", func(t *testing.T) {
+		got, err := ParseAs[*Pagination](url.Values{})
+		require.NoError(t, err)
+		assert.Equal(t, &Pagination{}, got)
+	})
+
+	t.Run("Invalid-Value", func(t *testing.T) {
+		got, err := ParseAs[*Pagination](url.Values{"page": {"x"}})
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Invalid-Type", func(t *testing.T) {
+		_, err := ParseAs[int](values)
+		assert.Error(t, err)
+
+		_, err = ParseAs[**Pagination](values)
+		assert.Error(t, err)
+	})
+
+	t.Run("Request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?page=3", nil)
+		got, err := ParseRequestAs[Pagination](req)
+		require.NoError(t, err)
+		assert.Equal(t, 3, got.Page)
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		got, err := ParseURLAs[Pagination]("http://example.com?limit=5")
+		require.NoError(t, err)
+		assert.Equal(t, 5, got.Limit)
+
+		_, err = ParseURLAs[Pagination]("ht@tp://example.com")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,161 @@
+package qparser
+
+import (
+	"iter"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// maxRawPairs is the largest number of pairs decoded in place. Lookups scan
+// every pair, so larger queries are parsed into url.Values whose map lookups
+// are cheaper.
+const maxRawPairs = 32
+
+// rawQuery is a KeySource reading a raw query string in place. Keys and values
+// are substrings of the query and are only unescaped, which allocates, when
+// they contain escapes. Values are unescaped on their first lookup.
+//
+// rawQuery values are pooled: get one with parseRawQuery and hand it back with
+// releaseQuery once decoding is done.
+type rawQuery struct {
+	kv        []string                // key then value of every pair, in query order
+	unescaped [maxRawPairs]bool       // values of kv already unescaped, by pair
+	buf       [2 * maxRawPairs]string // backs kv
+}
+
+var rawQueryPool = sync.Pool{
+	New: func() any { return new(rawQuery) },
+}
+
+// parseRawQuery tokenizes raw with the same rules as url.ParseQuery: pairs are
+// separated by '&', empty pairs are skipped and keys and values are unescaped
+// with url.QueryUnescape. Queries url.ParseQuery rejects, such as ones using ';'
+// as a separator, are rejected with the very same error. Queries of more than
+// maxRawPairs pairs are parsed with url.ParseQuery.
+func parseRawQuery(raw string) (KeySource, error) {
+	n := strings.Count(raw, "&") + 1
+	if n > maxRawPairs || strings.IndexByte(raw, ';') >= 0 || !validEscapes(raw) {
+		values, err := url.ParseQuery(raw)
+		if err != nil {
+			return nil, err
+		}
+		return ValuesSource(values), nil
+	}
+
+	q := rawQueryPool.Get().(*rawQuery)
+	q.kv = q.buf[:0]
+	for raw != "" {
+		var pair string
+		pair, raw, _ = strings.Cut(raw, "&")
+		if pair == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(pair, "=")
+		if needsUnescape(key) {
+			key, _ = url.QueryUnescape(key)
+		}
+		q.kv = append(q.kv, key, value)
+	}
+	return q, nil
+}
+
+// releaseQuery puts src back into the pool when parseRawQuery took it from
+// there. Neither src nor the slices it returned may be used afterwards.
+func releaseQuery(src KeySource) {
+	q, ok := src.(*rawQuery)
+	if !ok {
+		return
+	}
+	clear(q.kv) // drop the references to the query
+	q.kv = nil
+	clear(q.unescaped[:])
+	rawQueryPool.Put(q)
+}
+
+// Lookup returns a subslice of kv when key has a single value and a new slice
+// holding its values otherwise.
+func (q *rawQuery) Lookup(key string) ([]string, bool) {
+	first, n := 0, 0
+	for i := 0; i < len(q.kv); i += 2 {
+		if q.kv[i] == key {
+			if n == 0 {
+				first = i + 1
+			}
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return nil, false
+	case 1:
+		q.unescape(first)
+		return q.kv[first : first+1 : first+1], true
+	}
+
+	vals := make([]string, 0, n)
+	for i := first; i < len(q.kv); i += 2 {
+		if q.kv[i-1] == key {
+			q.unescape(i)
+			vals = append(vals, q.kv[i])
+		}
+	}
+	return vals, true
+}
+
+// unescape unescapes the value at kv[i] in place, once
+func (q *rawQuery) unescape(i int) {
+	if q.unescaped[i/2] || !needsUnescape(q.kv[i]) {
+		return
+	}
+	q.kv[i], _ = url.QueryUnescape(q.kv[i])
+	q.unescaped[i/2] = true
+}
+
+func (q *rawQuery) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for i := 0; i < len(q.kv); i += 2 {
+			if q.seenBefore(i) {
+				continue
+			}
+			if !yield(q.kv[i]) {
+				return
+			}
+		}
+	}
+}
+
+// seenBefore reports whether the key at kv[i] is also the key of an earlier pair
+func (q *rawQuery) seenBefore(i int) bool {
+	for j := 0; j < i; j += 2 {
+		if q.kv[j] == q.kv[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// needsUnescape reports whether s holds escapes or '+' encoded spaces
+func needsUnescape(s string) bool {
+	return strings.ContainsAny(s, "%+")
+}
+
+// validEscapes reports whether every '%' in s starts a valid escape sequence
+func validEscapes(s string) bool {
+	for i := strings.IndexByte(s, '%'); i >= 0; {
+		if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			return false
+		}
+		next := strings.IndexByte(s[i+3:], '%')
+		if next < 0 {
+			break
+		}
+		i += 3 + next
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,158 @@
+package qparser
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawQueryMatchesParseQuery(t *testing.T) {
+	queries := []string{
+		"",
+		"a=1",
+		"a=1&b=2&a=3",
+		"a=1&&b=2&",
+		"a&b=&=c",
+		"q=lorem+ipsum&name=John%20Doe&x=%2B",
+		"filter%5Bstatus%5D=active&filter[owner]=42",
+		"a=b=c",
+		"a=1;b=2",
+		"a=1&b=%zz&c=3",
+		"a=%4",
+		"a%=1",
+		"a=%41%42%43",
+		"a=%2541&a=%2542",
+	}
+
+	for _, raw := range queries {
+		t.Run(raw, func(t *testing.T) {
+			want, wantErr := url.ParseQuery(raw)
+
+			q, err := parseRawQuery(raw)
+			if wantErr != nil {
+				assert.Equal(t, wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var keys []string
+			for key := range q.Keys() {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			assert.Equal(t, slices.Sorted(maps.Keys(want)), slices.Compact(keys))
+
+			// Twice, values must only be unescaped once
+			for range 2 {
+				for key, vals := range want {
+					got, ok := q.Lookup(key)
+					assert.True(t, ok, key)
+					assert.Equal(t, vals, got, key)
+				}
+			}
+			_, ok := q.Lookup("missing")
+			assert.False(t, ok)
+		})
+	}
+
+	t.Run("Beyond-Raw-Pairs", func(t *testing.T) {
+		raw := strings.Repeat("k=v+1&", maxRawPairs) + "k=%2B"
+		want, err := url.ParseQuery(raw)
+		require.NoError(t, err)
+
+		q, err := parseRawQuery(raw)
+		require.NoError(t, err)
+		got, ok := q.Lookup("k")
+		assert.True(t, ok)
+		assert.Equal(t, want["k"], got)
+	})
+
+	t.Run("Too-Many-Params", func(t *testing.T) {
+		raw := strings.Repeat("a=1&", 10000) + "a=1"
+		_, wantErr := url.ParseQuery(raw)
+		_, err := parseRawQuery(raw)
+		assert.Equal(t, wantErr, err)
+	})
+}
+
+func TestParseQueryString(t *testing.T) {
+	type item struct {
+		Name string `qp:"name"`
+	}
+
+	type params struct {
+		Page   int               `qp:"page"`
+		Query  string            `qp:"q"`
+		IDs    []int             `qp:"ids"`
+		Items  []item            `qp:"items"`
+		Filter map[string]string `qp:"filter"`
+		Rest   url.Values        `qp:",remain"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		raw := "page=2&q=lorem+ipsum%21&ids=1,2&ids=3&items%5B0%5D%5Bname%5D=a&items[1][name]=b" +
+			"&filter[status]=active&filter[status]=ignored&other=x%20y"
+
+		var got params
+		require.NoError(t, ParseQueryString(raw, &got))
+
+		values, err := url.ParseQuery(raw)
+		require.NoError(t, err)
+		var want params
+		require.NoError(t, Parse(values, &want))
+
+		assert.Equal(t, want, got)
+		assert.Equal(t, "lorem ipsum!", got.Query)
+		assert.Equal(t, []int{1, 2, 3}, got.IDs)
+		assert.Equal(t, []item{{Name: "a"}, {Name: "b"}}, got.Items)
+		assert.Equal(t, map[string]string{"status": "active"}, got.Filter)
+		assert.Equal(t, url.Values{"other": {"x y"}}, got.Rest)
+	})
+
+	t.Run("Invalid-Query", func(t *testing.T) {
+		var got params
+		err := ParseQueryString("page=2;q=x", &got)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "semicolon")
+		assert.Zero(t, got.Page, "nothing is decoded")
+
+		err = ParseQueryString("q=%zz", &got)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid-Value", func(t *testing.T) {
+		var got params
+		err := ParseQueryString("page=x", &got)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		type strict struct {
+			Page int `qp:"page"`
+		}
+		var got strict
+		err := NewDecoder(WithStrict()).ParseQueryString("page=1&pgae=2&pgae=3", &got)
+
+		var unknownErr *UnknownParameterError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, []string{"pgae"}, unknownErr.Keys)
+	})
+
+	t.Run("Request", func(t *testing.T) {
+		var got params
+		req := httptest.NewRequest(http.MethodGet, "/?page=3&q=a%26b", nil)
+		require.NoError(t, ParseRequest(req, &got))
+		assert.Equal(t, 3, got.Page)
+		assert.Equal(t, "a&b", got.Query)
+
+		req = httptest.NewRequest(http.MethodGet, "/?page=3;q=x", nil)
+		assert.Error(t, ParseRequest(req, &got))
+	})
+}
@@ -0,0 +1,297 @@
+package qparser
+
+import (
+	"iter"
+	"maps"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Source gives a Decoder access to raw values by key, wherever they are
+// stored: a query string, headers, environment variables, a config map...
+// Values go through the same conversions, options and errors whatever the
+// Source.
+//
+// Lookup returns every value of key, in order, and whether key is present.
+// The Decoder never modifies the returned slice.
+type Source interface {
+	Lookup(key string) ([]string, bool)
+}
+
+// KeySource is a Source that can also enumerate its keys. Map fields, slices
+// of structs and remain fields discover their keys by enumeration, and strict
+// mode checks every key: decoding a plain Source, they see no keys and strict
+// mode fails.
+type KeySource interface {
+	Source
+
+	// Keys yields every distinct key of the source.
+	Keys() iter.Seq[string]
+}
+
+// ValuesSource is a KeySource backed by url.Values.
+type ValuesSource url.Values
+
+func (v ValuesSource) Lookup(key string) ([]string, bool) {
+	vals, ok := v[key]
+	return vals, ok
+}
+
+func (v ValuesSource) Keys() iter.Seq[string] {
+	return maps.Keys(v)
+}
+
+// HeaderSource is a KeySource backed by an http.Header. Keys are looked up
+// case-insensitively, like http.Header.Values does, and enumerated in their
+// canonical form.
+type HeaderSource http.Header
+
+func (h HeaderSource) Lookup(key string) ([]string, bool) {
+	vals, ok := h[textproto.CanonicalMIMEHeaderKey(key)]
+	return vals, ok
+}
+
+func (h HeaderSource) Keys() iter.Seq[string] {
+	return maps.Keys(h)
+}
+
+func (h HeaderSource) normalizeKey(key string) string {
+	return textproto.CanonicalMIMEHeaderKey(key)
+}
+
+// EnvSource is a KeySource over the environment variables whose name starts
+// with the prefix it holds, e.g. EnvSource("APP_"). The key "PORT" is read
+// from APP_PORT, and each variable holds a single value. Variables are read
+// when looked up, not when the EnvSource is created.
+type EnvSource string
+
+func (e EnvSource) Lookup(key string) ([]string, bool) {
+	if v, ok := os.LookupEnv(string(e) + key); ok {
+		return []string{v}, true
+	}
+	return nil, false
+}
+
+func (e EnvSource) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, kv := range os.Environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			key, ok := strings.CutPrefix(name, string(e))
+			if !ok || key == "" {
+				continue
+			}
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Merge returns a KeySource combining sources: the values of a key are those
+// of every source holding it, in order, as if each source had been added in
+// turn to a single url.Values. Its keys are those of the KeySources among
+// sources.
+func Merge(sources ...Source) KeySource {
+	return mergedSource(sources)
+}
+
+type mergedSource []Source
+
+func (m mergedSource) Lookup(key string) ([]string, bool) {
+	var vals []string
+	found := 0
+	for _, src := range m {
+		v, ok := src.Lookup(key)
+		if !ok {
+			continue
+		}
+		// The slice of the first source is only copied if another adds to it
+		switch found {
+		case 0:
+			vals = v
+		case 1:
+			vals = append(slices.Clip(vals), v...)
+		default:
+			vals = append(vals, v...)
+		}
+		found++
+	}
+	return vals, found > 0
+}
+
+func (m mergedSource) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		seen := make(map[string]struct{})
+		for _, src := range m {
+			ks, ok := src.(KeySource)
+			if !ok {
+				continue
+			}
+			for key := range ks.Keys() {
+				if _, dup := seen[key]; dup {
+					continue
+				}
+				seen[key] = struct{}{}
+				if !yield(key) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// normalizeKey returns the form the first of m enumerating key differently
+// gives it, key itself if none does
+func (m mergedSource) normalizeKey(key string) string {
+	for _, src := range m {
+		if n, ok := src.(keyNormalizer); ok {
+			if nk := n.normalizeKey(key); nk != key {
+				return nk
+			}
+		}
+	}
+	return key
+}
+
+// Layered returns a KeySource stacking sources by precedence: each key is read
+// from the first source holding it, whose values replace those of the sources
+// after it. List the sources from the highest precedence to the lowest, e.g.
+// Layered(query, config, defaults). Its keys are those of the KeySources among
+// sources.
+//
+// A FieldError for a value read from a Layered source names the layer that
+// supplied it in its Source: the name given with Named, or "layer N" for the
+// Nth of sources.
+func Layered(sources ...Source) KeySource {
+	return layeredSource{sources: sources}
+}
+
+// LayeredLastWins is Layered with the opposite precedence, each key read from
+// the last source holding it. List the sources from the lowest precedence to
+// the highest, e.g. LayeredLastWins(defaults, config, query).
+func LayeredLastWins(sources ...Source) KeySource {
+	return layeredSource{sources: sources, lastWins: true}
+}
+
+// Named names src for the FieldError.Source of the values it supplies, see
+// Layered.
+func Named(name string, src Source) KeySource {
+	return namedSource{name: name, src: keySource(src)}
+}
+
+// keyNormalizer is implemented by sources enumerating a key in another form
+// than the one it is looked up with, e.g. HeaderSource canonicalizing it.
+// Consumed keys are recorded in the enumerated form too, so that strict mode
+// and remain fields recognise them.
+type keyNormalizer interface {
+	normalizeKey(key string) string
+}
+
+// layerNamer is implemented by sources telling which layer holds a key
+type layerNamer interface {
+	layerOf(key string) string
+}
+
+type layeredSource struct {
+	sources  []Source
+	lastWins bool
+}
+
+// winner returns the index of the source a key is read from and its values,
+// -1 if no source holds it
+func (l layeredSource) winner(key string) (int, []string) {
+	for n := range l.sources {
+		i := n
+		if l.lastWins {
+			i = len(l.sources) - 1 - n
+		}
+		if vals, ok := l.sources[i].Lookup(key); ok {
+			return i, vals
+		}
+	}
+	return -1, nil
+}
+
+func (l layeredSource) Lookup(key string) ([]string, bool) {
+	i, vals := l.winner(key)
+	return vals, i >= 0
+}
+
+func (l layeredSource) Keys() iter.Seq[string] {
+	return mergedSource(l.sources).Keys()
+}
+
+func (l layeredSource) normalizeKey(key string) string {
+	return mergedSource(l.sources).normalizeKey(key)
+}
+
+func (l layeredSource) layerOf(key string) string {
+	i, _ := l.winner(key)
+	if i < 0 {
+		return ""
+	}
+	if name := l.namedLayer(i, key); name != "" {
+		return name
+	}
+	return "layer " + strconv.Itoa(i+1)
+}
+
+// namedLayer returns the name given with Named to the layer of key within the
+// ith source, looking through nested Layered sources
+func (l layeredSource) namedLayer(i int, key string) string {
+	switch src := l.sources[i].(type) {
+	case namedSource:
+		return src.name
+	case layeredSource:
+		if j, _ := src.winner(key); j >= 0 {
+			return src.namedLayer(j, key)
+		}
+	}
+	return ""
+}
+
+type namedSource struct {
+	name string
+	src  KeySource
+}
+
+func (n namedSource) Lookup(key string) ([]string, bool) {
+	return n.src.Lookup(key)
+}
+
+func (n namedSource) Keys() iter.Seq[string] {
+	return n.src.Keys()
+}
+
+func (n namedSource) normalizeKey(key string) string {
+	if kn, ok := n.src.(keyNormalizer); ok {
+		return kn.normalizeKey(key)
+	}
+	return key
+}
+
+func (n namedSource) layerOf(string) string {
+	return n.name
+}
+
+// keySource returns src as a KeySource, enumerating no keys when src cannot
+func keySource(src Source) KeySource {
+	if ks, ok := src.(KeySource); ok {
+		return ks
+	}
+	return lookupOnly{src}
+}
+
+// lookupOnly is a Source that cannot enumerate its keys
+type lookupOnly struct {
+	Source
+}
+
+func (lookupOnly) Keys() iter.Seq[string] {
+	return func(func(string) bool) {}
+}
@@ -0,0 +1,236 @@
+package qparser
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapSource is a Source unable to enumerate its keys
+type mapSource map[string]string
+
+func (m mapSource) Lookup(key string) ([]string, bool) {
+	v, ok := m[key]
+	if !ok {
+		return nil, false
+	}
+	return []string{v}, true
+}
+
+func TestParseSource(t *testing.T) {
+	type config struct {
+		Port    int            `qp:"PORT,default=8080"`
+		Debug   bool           `qp:"DEBUG"`
+		Hosts   []string       `qp:"HOSTS"`
+		Limits  map[string]int `qp:"LIMIT"`
+		Unknown url.Values     `qp:",remain"`
+	}
+
+	t.Run("Values", func(t *testing.T) {
+		var got config
+		require.NoError(t, ParseSource(ValuesSource{"PORT": {"9000"}, "LIMIT[rps]": {"5"}, "X": {"y"}}, &got))
+		assert.Equal(t, 9000, got.Port)
+		assert.Equal(t, map[string]int{"rps": 5}, got.Limits)
+		assert.Equal(t, url.Values{"X": {"y"}}, got.Unknown)
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		type params struct {
+			Tenant string   `qp:"x-tenant"`
+			Langs  []string `qp:"Accept-Language"`
+		}
+		h := http.Header{}
+		h.Set("X-Tenant", "acme")
+		h.Add("Accept-Language", "en")
+		h.Add("Accept-Language", "fr")
+
+		var got params
+		require.NoError(t, ParseSource(HeaderSource(h), &got))
+		assert.Equal(t, params{Tenant: "acme", Langs: []string{"en", "fr"}}, got)
+		assert.ElementsMatch(t, []string{"X-Tenant", "Accept-Language"}, slices.Collect(HeaderSource(h).Keys()))
+	})
+
+	t.Run("Header-Consumed", func(t *testing.T) {
+		type params struct {
+			Tenant string      `qp:"x-tenant"`
+			Rest   http.Header `qp:",remain"`
+		}
+		h := http.Header{}
+		h.Set("X-Tenant", "acme")
+		h.Set("X-Request-Id", "42")
+
+		var got params
+		require.NoError(t, ParseSource(HeaderSource(h), &got))
+		assert.Equal(t, "acme", got.Tenant)
+		assert.Equal(t, http.Header{"X-Request-Id": {"42"}}, got.Rest, "the tenant header is not captured again")
+
+		var strict struct {
+			Tenant string `qp:"x-tenant"`
+		}
+		require.NoError(t, NewDecoder(WithStrict()).ParseSource(HeaderSource(http.Header{"X-Tenant": {"acme"}}), &strict))
+		src := Layered(Named("headers", HeaderSource(http.Header{"X-Tenant": {"acme"}})), ValuesSource{"x-tenant": {"other"}})
+		require.NoError(t, NewDecoder(WithStrict()).ParseSource(src, &strict))
+		assert.Equal(t, "acme", strict.Tenant)
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("QPTEST_PORT", "7000")
+		t.Setenv("QPTEST_HOSTS", "a.example, b.example")
+		t.Setenv("QPTEST_LIMIT[burst]", "10")
+		t.Setenv("QPTEST_", "ignored")
+
+		var got config
+		require.NoError(t, ParseSource(EnvSource("QPTEST_"), &got))
+		assert.Equal(t, 7000, got.Port)
+		assert.Equal(t, []string{"a.example", "b.example"}, got.Hosts)
+		assert.Equal(t, map[string]int{"burst": 10}, got.Limits)
+
+		keys := slices.Sorted(EnvSource("QPTEST_").Keys())
+		assert.Equal(t, []string{"HOSTS", "LIMIT[burst]", "PORT"}, keys)
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		defaults := ValuesSource{"PORT": {"1"}, "HOSTS": {"a"}}
+		overrides := mapSource{"HOSTS": "b", "DEBUG": "true"}
+		src := Merge(defaults, overrides, ValuesSource{"HOSTS": {"c"}})
+
+		vals, ok := src.Lookup("HOSTS")
+		require.True(t, ok)
+		assert.Equal(t, []string{"a", "b", "c"}, vals)
+		assert.Equal(t, []string{"a"}, defaults["HOSTS"], "sources are not modified")
+
+		_, ok = src.Lookup("MISSING")
+		assert.False(t, ok)
+		assert.ElementsMatch(t, []string{"PORT", "HOSTS"}, slices.Collect(src.Keys()))
+
+		var got config
+		require.NoError(t, ParseSource(src, &got))
+		assert.Equal(t, 1, got.Port)
+		assert.True(t, got.Debug)
+		assert.Equal(t, []string{"a", "b", "c"}, got.Hosts)
+	})
+
+	t.Run("Lookup-Only", func(t *testing.T) {
+		var got config
+		require.NoError(t, ParseSource(mapSource{"PORT": "81", "LIMIT[rps]": "5"}, &got))
+		assert.Equal(t, 81, got.Port)
+		assert.Nil(t, got.Limits, "maps need a KeySource")
+		assert.Nil(t, got.Unknown, "remain fields need a KeySource")
+
+		err := NewDecoder(WithStrict()).ParseSource(mapSource{"PORT": "81"}, &got)
+		assert.EqualError(t, err, "strict mode requires a KeySource")
+	})
+
+	t.Run("Field-Error", func(t *testing.T) {
+		var got config
+		err := ParseSource(mapSource{"PORT": "eighty"}, &got)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.EqualError(t, err, `failed to parse "config.Port": invalid value: eighty`)
+	})
+}
+
+func TestLayered(t *testing.T) {
+	type config struct {
+		Port   int            `qp:"PORT,default=8080"`
+		Hosts  []string       `qp:"HOSTS"`
+		Limits map[string]int `qp:"LIMIT"`
+	}
+	defaults := ValuesSource{"PORT": {"1"}, "HOSTS": {"a", "b"}, "LIMIT[rps]": {"5"}}
+	settings := mapSource{"HOSTS": "c"}
+	query := ValuesSource{"PORT": {"3"}, "LIMIT[burst]": {"x"}}
+
+	t.Run("First-Wins", func(t *testing.T) {
+		src := Layered(query, settings, defaults)
+		vals, ok := src.Lookup("HOSTS")
+		require.True(t, ok)
+		assert.Equal(t, []string{"c"}, vals, "values are not merged")
+		_, ok = src.Lookup("MISSING")
+		assert.False(t, ok)
+		assert.ElementsMatch(t, []string{"PORT", "HOSTS", "LIMIT[rps]", "LIMIT[burst]"}, slices.Collect(src.Keys()))
+
+		var got config
+		require.NoError(t, ParseSource(Layered(ValuesSource{"PORT": {"3"}}, settings, defaults), &got))
+		assert.Equal(t, config{Port: 3, Hosts: []string{"c"}, Limits: map[string]int{"rps": 5}}, got)
+	})
+
+	t.Run("Last-Wins", func(t *testing.T) {
+		src := LayeredLastWins(defaults, settings, ValuesSource{"PORT": {"3"}})
+
+		var got config
+		require.NoError(t, ParseSource(src, &got))
+		assert.Equal(t, config{Port: 3, Hosts: []string{"c"}, Limits: map[string]int{"rps": 5}}, got)
+
+		got = config{}
+		require.NoError(t, ParseSource(LayeredLastWins(ValuesSource{"PORT": {"3"}}, defaults), &got))
+		assert.Equal(t, 1, got.Port)
+	})
+
+	t.Run("Error-Layer", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			src    KeySource
+			field  string
+			source string
+		}{
+			{"Named", Layered(Named("query", ValuesSource{"PORT": {"x"}}), Named("defaults", defaults)), "config.Port", "query"},
+			{"Named-Lower", LayeredLastWins(Named("defaults", ValuesSource{"PORT": {"x"}}), Named("config", settings)), "config.Port", "defaults"},
+			{"Unnamed", Layered(settings, ValuesSource{"PORT": {"x"}}), "config.Port", "layer 2"},
+			{"Nested", Layered(settings, Layered(Named("env", mapSource{"PORT": "x"}), defaults)), "config.Port", "env"},
+			{"Nested-Unnamed", Layered(settings, Layered(ValuesSource{"PORT": {"x"}}), defaults), "config.Port", "layer 2"},
+			{"Map-Entry", Layered(Named("query", query), Named("defaults", defaults)), "config.Limits[burst]", "query"},
+			{"Named-Only", Named("env", mapSource{"PORT": "x"}), "config.Port", "env"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got config
+				err := ParseSource(tt.src, &got)
+				assert.ErrorIs(t, err, ErrInvalidValue)
+
+				var fieldErr *FieldError
+				require.ErrorAs(t, err, &fieldErr)
+				assert.Equal(t, tt.field, fieldErr.FieldName)
+				assert.Equal(t, tt.source, fieldErr.Source)
+			})
+		}
+	})
+
+	t.Run("Error-Message", func(t *testing.T) {
+		var got config
+		err := ParseSource(Layered(Named("config", mapSource{"PORT": "eighty"}), defaults), &got)
+		assert.EqualError(t, err, `failed to parse "config.Port" from config: invalid value: eighty`)
+	})
+}
+
+func TestParseAllocs(t *testing.T) {
+	type params struct {
+		Page   int    `qp:"page"`
+		Query  string `qp:"q"`
+		Nested struct {
+			Limit int `qp:"limit"`
+		} `qp:"n"`
+	}
+	values := url.Values{"page": {"1"}, "q": {"x"}, "n[limit]": {"2"}}
+
+	// Decoding scalars into an existing value must not allocate: the
+	// decode state in particular has to stay on the stack.
+	var dst params
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = Parse(values, &dst)
+	})
+	assert.Zero(t, allocs)
+	assert.Equal(t, 2, dst.Nested.Limit)
+
+	// Same for a raw query without escapes, read in place
+	dst = params{}
+	allocs = testing.AllocsPerRun(100, func() {
+		_ = ParseQueryString("page=1&q=abc&n[limit]=5", &dst)
+	})
+	assert.Zero(t, allocs)
+	assert.Equal(t, params{Page: 1, Query: "abc", Nested: struct {
+		Limit int `qp:"limit"`
+	}{Limit: 5}}, dst)
+}
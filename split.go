@@ -0,0 +1,114 @@
+package qparser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// validSeparator reports whether sep can split values: a printable byte,
+// other than the backslash escaping separators within elements
+func validSeparator(sep byte) bool {
+	return sep > ' ' && sep != '\\'
+}
+
+// resolveSplit sets how a slice field splits its values into elements: on the
+// separator of the "sep=" option of its tag if any, on the Decoder's
+// otherwise, or not at all with the "nosplit" option. Both options are only
+// accepted by slices decoded element by element.
+func (d *Decoder) resolveSplit(field *fieldInfo, opts tagOptions) error {
+	field.sep = d.separator
+	if !opts.hasSep && !opts.nosplit {
+		return nil
+	}
+	if !splitsValues(field) {
+		return fmt.Errorf("%w: options \"sep\" and \"nosplit\" require a slice field, got %v", ErrInvalidTag, field.typ)
+	}
+	if opts.nosplit && (opts.hasSep || opts.join) {
+		return fmt.Errorf("%w: option \"nosplit\" cannot be combined with \"sep\" or \"join\"", ErrInvalidTag)
+	}
+	if opts.hasSep {
+		field.sep = opts.sep
+	}
+	field.nosplit = opts.nosplit
+	return nil
+}
+
+// splitsValues reports whether field is a slice, or pointer to slice, whose
+// elements are decoded one by one from separated values
+func splitsValues(field *fieldInfo) bool {
+	if field.unmarshal != unmarshalNone {
+		return false
+	}
+	typ := field.typ
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Slice && field.mapValue == nil && field.structElem == nil && !field.remain && !field.file
+}
+
+// cutElem returns the element of v starting at i, up to the first unescaped
+// sep or the end of v, and the index following it. The element is trimmed but
+// not unescaped, escaped reports whether it needs unescapeElem.
+//
+// A backslash escapes the separator or another backslash, e.g. `a\,b` is the
+// single element "a,b". Any other backslash is kept as is.
+func cutElem(v string, i int, sep byte) (elem string, next int, escaped bool) {
+	start := i
+	for ; i < len(v); i++ {
+		c := v[i]
+		if c == '\\' && i+1 < len(v) && (v[i+1] == sep || v[i+1] == '\\') {
+			escaped = true
+			i++
+			continue
+		}
+		if c == sep {
+			return trimElem(v[start:i]), i + 1, escaped
+		}
+	}
+	return trimElem(v[start:]), i, escaped
+}
+
+// unescapeElem removes the escapes of an element returned by cutElem
+func unescapeElem(elem string, sep byte) string {
+	var b strings.Builder
+	b.Grow(len(elem))
+	for i := 0; i < len(elem); i++ {
+		c := elem[i]
+		if c == '\\' && i+1 < len(elem) && (elem[i+1] == sep || elem[i+1] == '\\') {
+			i++
+			c = elem[i]
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// escapeElem escapes the separators and backslashes of an element so that
+// cutElem and unescapeElem give it back whole, the inverse of both
+func escapeElem(elem string, sep byte) string {
+	if strings.IndexByte(elem, sep) < 0 && strings.IndexByte(elem, '\\') < 0 {
+		return elem
+	}
+	var b strings.Builder
+	b.Grow(len(elem) + 2)
+	for i := 0; i < len(elem); i++ {
+		if c := elem[i]; c == sep || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(elem[i])
+	}
+	return b.String()
+}
+
+// trimElem trims the whitespace and control bytes surrounding an element
+func trimElem(s string) string {
+	start, end := 0, len(s)
+	for start < end && s[start] <= ' ' {
+		start++
+	}
+	for start < end && s[end-1] <= ' ' {
+		end--
+	}
+	return s[start:end]
+}
@@ -0,0 +1,150 @@
+package qparser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type articleQuery struct {
+	Tags     []string            `qp:"tags,nosplit"`
+	IDs      []int               `qp:"ids,sep=|"`
+	Owners   *[]string           `qp:"owners,sep=;"`
+	Keywords []string            `qp:"kw"`
+	Authors  []string            `qp:"authors,sep=;,join"`
+	Facets   map[string][]string `qp:"facets,sep=|"`
+}
+
+func TestSplit(t *testing.T) {
+	t.Run("Options", func(t *testing.T) {
+		var got articleQuery
+		require.NoError(t, Parse(url.Values{
+			"tags":          {"hello, world", " ", "go"},
+			"ids":           {"1|2", "3"},
+			"owners":        {"ann, bob;carl"},
+			"facets[color]": {"red|dark, blue"},
+		}, &got))
+		assert.Equal(t, []string{"hello, world", "go"}, got.Tags)
+		assert.Equal(t, []string{"ann, bob", "carl"}, *got.Owners)
+		assert.Equal(t, map[string][]string{"color": {"red", "dark, blue"}}, got.Facets)
+		assert.Equal(t, []int{1, 2, 3}, got.IDs)
+
+		err := Parse(url.Values{"ids": {"1|2,3"}}, &got)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.ErrorContains(t, err, "element [1]")
+	})
+
+	t.Run("Escapes", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			value string
+			want  []string
+		}{
+			{"Separator", `a\,b,c`, []string{"a,b", "c"}},
+			{"Backslash", `a\\,b`, []string{`a\`, "b"}},
+			{"Literal-Backslash", `C:\dir,d\`, []string{`C:\dir`, `d\`}},
+			{"Only-Escapes", `\,,\\`, []string{",", `\`}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got articleQuery
+				require.NoError(t, Parse(url.Values{"kw": {tt.value}}, &got))
+				assert.Equal(t, tt.want, got.Keywords)
+			})
+		}
+
+		var got articleQuery
+		require.NoError(t, Parse(url.Values{"owners": {`a\;b;c\,d`}, "tags": {`x\,y`}}, &got))
+		assert.Equal(t, []string{"a;b", `c\,d`}, *got.Owners)
+		assert.Equal(t, []string{`x\,y`}, got.Tags)
+	})
+
+	t.Run("Decoder-Separator", func(t *testing.T) {
+		var got articleQuery
+		d := NewDecoder(WithSeparator('/'))
+		require.NoError(t, d.Parse(url.Values{"kw": {`a/b\/c`}, "ids": {"4|5"}}, &got))
+		assert.Equal(t, []string{"a", "b/c"}, got.Keywords)
+		assert.Equal(t, []int{4, 5}, got.IDs)
+	})
+
+	t.Run("Encode", func(t *testing.T) {
+		owners := []string{"a;b", "c"}
+		in := articleQuery{
+			Tags:     []string{"hello, world"},
+			IDs:      []int{1, 2},
+			Owners:   &owners,
+			Keywords: []string{"x,y", `z\`},
+			Authors:  []string{"ann;bob", "carl"},
+			Facets:   map[string][]string{"color": {"red|blue"}},
+		}
+		values, err := Encode(in)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"tags":          {"hello, world"},
+			"ids":           {"1", "2"},
+			"owners":        {`a\;b`, "c"},
+			"kw":            {`x\,y`, `z\\`},
+			"authors":       {`ann\;bob;carl`},
+			"facets[color]": {`red\|blue`},
+		}, values)
+
+		var got articleQuery
+		require.NoError(t, Parse(values, &got))
+		assert.Equal(t, in, got)
+	})
+
+	t.Run("Bound-Field", func(t *testing.T) {
+		type params struct {
+			Langs []string `header:"Accept-Language,sep=;"`
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", "en, fr;de")
+
+		var got params
+		require.NoError(t, Bind(req, &got))
+		assert.Equal(t, []string{"en, fr", "de"}, got.Langs)
+	})
+
+	t.Run("Invalid-Tags", func(t *testing.T) {
+		tests := []struct {
+			name string
+			dst  any
+		}{
+			{"Missing-Separator", &struct {
+				S []string `qp:"s,sep="`
+			}{}},
+			{"Long-Separator", &struct {
+				S []string `qp:"s,sep=||"`
+			}{}},
+			{"Space-Separator", &struct {
+				S []string `qp:"s,sep= "`
+			}{}},
+			{"Backslash-Separator", &struct {
+				S []string `qp:"s,sep=\\"`
+			}{}},
+			{"Scalar", &struct {
+				S string `qp:"s,nosplit"`
+			}{}},
+			{"Nosplit-Sep", &struct {
+				S []string `qp:"s,nosplit,sep=|"`
+			}{}},
+			{"Nosplit-Join", &struct {
+				S []string `qp:"s,nosplit,join"`
+			}{}},
+			{"Struct-Slice", &struct {
+				Items []struct {
+					A int `qp:"a"`
+				} `qp:"items,sep=|"`
+			}{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.ErrorIs(t, Parse(url.Values{}, tt.dst), ErrInvalidTag)
+			})
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package qparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tagOptions holds the options following the key in a struct tag,
+// e.g. `qp:"limit,default=20"`.
+type tagOptions struct {
+	hasDefault   bool
+	defaultValue string
+	required     bool // key must be present
+	nonempty     bool // key, when present, must carry a non-blank value
+	omitempty    bool // Encoder skips the field when it holds its zero value
+	join         bool // Encoder joins slice elements into one separated value
+	remain       bool // field receives every key no other field consumes
+	hasEnum      bool
+	enumValues   string // '|' separated values the field accepts
+	fold         bool   // enum values are compared case-insensitively
+	hasDup       bool
+	dup          DuplicatePolicy // how a key sent several times is handled
+	hasSep       bool
+	sep          byte // splits slice elements instead of the Decoder's separator
+	nosplit      bool // every value of a slice field is a single element
+
+	// rules holds the validation options, comma separated as written, e.g.
+	// "min=1,max=100". They are compiled against the field type by
+	// compileRules.
+	rules string
+}
+
+// parseTag splits a struct tag value into the query key and its options.
+// Unknown options are rejected so typos surface when struct metadata is built.
+func parseTag(tag string) (string, tagOptions, error) {
+	var opts tagOptions
+	key, rest, _ := strings.Cut(tag, ",")
+
+	for rest != "" {
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+
+		name, value, hasValue := strings.Cut(opt, "=")
+		switch name {
+		case "default":
+			if !hasValue {
+				return "", opts, fmt.Errorf("%w: option %q requires a value", ErrInvalidTag, name)
+			}
+			opts.hasDefault = true
+			opts.defaultValue = value
+		case "required":
+			opts.required = true
+		case "nonempty":
+			opts.nonempty = true
+		case "omitempty":
+			opts.omitempty = true
+		case "join":
+			opts.join = true
+		case "remain":
+			opts.remain = true
+		case "enum":
+			if !hasValue || value == "" {
+				return "", opts, fmt.Errorf("%w: option %q requires a value", ErrInvalidTag, name)
+			}
+			opts.hasEnum = true
+			opts.enumValues = value
+		case "fold":
+			opts.fold = true
+		case "dup":
+			policy, ok := parseDuplicatePolicy(value)
+			if !ok {
+				return "", opts, fmt.Errorf("%w: option %q requires one of first, last, error or join", ErrInvalidTag, name)
+			}
+			opts.hasDup = true
+			opts.dup = policy
+		case "sep":
+			if len(value) != 1 || !validSeparator(value[0]) {
+				return "", opts, fmt.Errorf("%w: option %q requires a single printable byte other than a backslash", ErrInvalidTag, name)
+			}
+			opts.hasSep = true
+			opts.sep = value[0]
+		case "nosplit":
+			opts.nosplit = true
+		default:
+			if isRuleOption(name) {
+				if opts.rules != "" {
+					opts.rules += ","
+				}
+				opts.rules += opt
+				continue
+			}
+			return "", opts, fmt.Errorf("%w: unknown option %q", ErrInvalidTag, opt)
+		}
+	}
+
+	if opts.required && opts.hasDefault {
+		return "", opts, fmt.Errorf("%w: options \"required\" and \"default\" are mutually exclusive", ErrInvalidTag)
+	}
+
+	return key, opts, nil
+}
@@ -37,6 +37,22 @@ var (
 	}
 )
 
+// parseTime parses value with the Decoder's configured layouts, falling back
+// to the built-in format detection when none are configured.
+func (d *Decoder) parseTime(value string) (time.Time, error) {
+	if len(d.timeLayouts) == 0 {
+		return parseTime(value)
+	}
+
+	value = strings.TrimSpace(value)
+	for _, layout := range d.timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: unable to parse with configured date formats: %s", ErrInvalidValue, value)
+}
+
 func parseTime(value string) (time.Time, error) {
 	value = strings.TrimSpace(value)
 
@@ -0,0 +1,89 @@
+package qparser
+
+import (
+	"encoding"
+	"reflect"
+	"time"
+)
+
+// Unmarshaler is implemented by types that decode themselves from query
+// parameters. UnmarshalQuery receives every raw value sent for the field's key,
+// in order, without any separator splitting.
+//
+// When the type is used as a slice element, UnmarshalQuery is called once per
+// element with that single element as its only value. UnmarshalQuery must not
+// retain values, which may be reused once it returns, only the strings it holds.
+//
+// Unmarshaler takes precedence over encoding.TextUnmarshaler, which is also
+// supported and receives the first value of the key.
+type Unmarshaler interface {
+	UnmarshalQuery(values []string) error
+}
+
+// unmarshalKind records which decoding interface a type implements, resolved
+// once when struct metadata is built.
+type unmarshalKind uint8
+
+const (
+	unmarshalNone  unmarshalKind = iota
+	unmarshalQuery               // implements Unmarshaler
+	unmarshalText                // implements encoding.TextUnmarshaler
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unmarshalKindOf reports the decoding interface implemented by t or *t.
+// time.Time is excluded so it keeps the multi-format parsing of parseTime.
+func unmarshalKindOf(t reflect.Type) unmarshalKind {
+	if t == timeType || t.Kind() == reflect.Interface {
+		return unmarshalNone
+	}
+	pt := reflect.PointerTo(t)
+	switch {
+	case t.Implements(unmarshalerType) || pt.Implements(unmarshalerType):
+		return unmarshalQuery
+	case t.Implements(textUnmarshalerType) || pt.Implements(textUnmarshalerType):
+		return unmarshalText
+	}
+	return unmarshalNone
+}
+
+// unmarshal decodes vals into the addressable fv through the interface
+// selected by kind. Errors returned by the implementation are passed through.
+// A text value is left untouched when vals is empty.
+func unmarshal(kind unmarshalKind, fv reflect.Value, vals []string) error {
+	if kind == unmarshalText && len(vals) == 0 {
+		return nil
+	}
+	target := fv.Addr().Interface()
+	switch kind {
+	case unmarshalQuery:
+		return target.(Unmarshaler).UnmarshalQuery(vals)
+	case unmarshalText:
+		return target.(encoding.TextUnmarshaler).UnmarshalText([]byte(vals[0]))
+	}
+	return nil
+}
+
+// setUnmarshalerField decodes a field whose type, or pointed-to type,
+// implements Unmarshaler or encoding.TextUnmarshaler.
+func setUnmarshalerField(fv reflect.Value, ft reflect.Type, kind unmarshalKind, vals []string) error {
+	if ft.Kind() != reflect.Ptr {
+		return unmarshal(kind, fv, vals)
+	}
+
+	// Pointers follow the usual rule and stay nil for an empty text value
+	if kind == unmarshalText && (len(vals) == 0 || vals[0] == "") {
+		return nil
+	}
+	elemVal := reflect.New(ft.Elem())
+	if err := unmarshal(kind, elemVal.Elem(), vals); err != nil {
+		return err
+	}
+	fv.Set(elemVal)
+	return nil
+}
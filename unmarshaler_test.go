@@ -0,0 +1,207 @@
+package qparser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// status is an int enum decoded from its name through encoding.TextUnmarshaler
+type status int
+
+const (
+	statusUnknown status = iota
+	statusActive
+	statusArchived
+)
+
+var errUnknownStatus = errors.New("unknown status")
+
+func (s *status) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "active":
+		*s = statusActive
+	case "archived":
+		*s = statusArchived
+	default:
+		return fmt.Errorf("%w: %s", errUnknownStatus, text)
+	}
+	return nil
+}
+
+// money is a struct decoded from "<amount> <currency>"
+type money struct {
+	Amount   int
+	Currency string
+}
+
+func (m *money) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d %s", &m.Amount, &m.Currency)
+	return err
+}
+
+// rawList keeps every raw value of its key, implementing Unmarshaler
+type rawList struct {
+	Values []string
+}
+
+func (r *rawList) UnmarshalQuery(values []string) error {
+	r.Values = append(r.Values, values...)
+	return nil
+}
+
+// upper implements both interfaces, Unmarshaler must win
+type upper string
+
+func (u *upper) UnmarshalText(text []byte) error {
+	*u = upper("text:" + string(text))
+	return nil
+}
+
+func (u *upper) UnmarshalQuery(values []string) error {
+	*u = upper(strings.ToUpper(strings.Join(values, "+")))
+	return nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	type params struct {
+		Addr     netip.Addr    `qp:"addr"`
+		AddrPtr  *netip.Addr   `qp:"addr_ptr"`
+		Addrs    []netip.Addr  `qp:"addrs"`
+		AddrPtrs []*netip.Addr `qp:"addr_ptrs"`
+		IP       net.IP        `qp:"ip"`
+		Status   status        `qp:"status"`
+		Statuses *[]status     `qp:"statuses"`
+		Price    money         `qp:"price"`
+		Missing  *netip.Addr   `qp:"missing"`
+		Empty    *netip.Addr   `qp:"empty"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		values, err := url.ParseQuery(
+			"addr=10.0.0.1&addr_ptr=::1&addrs=10.0.0.1,10.0.0.2&addrs=10.0.0.3&addr_ptrs=10.0.0.4" +
+				"&ip=192.168.1.1&status=active&statuses=active,archived&price=100%20USD&empty=",
+		)
+		require.NoError(t, err)
+
+		var p params
+		err = Parse(values, &p)
+		require.NoError(t, err)
+
+		assert.Equal(t, netip.MustParseAddr("10.0.0.1"), p.Addr)
+		assert.Equal(t, ptr(netip.MustParseAddr("::1")), p.AddrPtr)
+		assert.Equal(t, []netip.Addr{
+			netip.MustParseAddr("10.0.0.1"),
+			netip.MustParseAddr("10.0.0.2"),
+			netip.MustParseAddr("10.0.0.3"),
+		}, p.Addrs)
+		assert.Equal(t, []*netip.Addr{ptr(netip.MustParseAddr("10.0.0.4"))}, p.AddrPtrs)
+		assert.True(t, net.ParseIP("192.168.1.1").Equal(p.IP))
+		assert.Equal(t, statusActive, p.Status)
+		assert.Equal(t, &[]status{statusActive, statusArchived}, p.Statuses)
+		assert.Equal(t, money{Amount: 100, Currency: "USD"}, p.Price)
+		assert.Nil(t, p.Missing)
+		assert.Nil(t, p.Empty)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var p params
+		err := Parse(url.Values{"status": {"deleted"}}, &p)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, errUnknownStatus)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "params.Status", fieldErr.FieldName)
+	})
+
+	t.Run("Invalid-Slice-Element", func(t *testing.T) {
+		var p params
+		err := Parse(url.Values{"addrs": {"10.0.0.1,not-an-ip"}}, &p)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "element [1]")
+	})
+
+	t.Run("Present-Without-Values", func(t *testing.T) {
+		var p params
+		require.NoError(t, Parse(url.Values{"addr": {}, "addr_ptr": {}, "status": {}}, &p))
+		assert.Equal(t, netip.Addr{}, p.Addr)
+		assert.Nil(t, p.AddrPtr)
+		assert.Equal(t, statusUnknown, p.Status)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		type withDefault struct {
+			Status status `qp:"status,default=archived"`
+			IP     net.IP `qp:"ip,default=127.0.0.1"`
+		}
+
+		var p withDefault
+		require.NoError(t, Parse(url.Values{}, &p))
+		assert.Equal(t, statusArchived, p.Status)
+		assert.True(t, net.ParseIP("127.0.0.1").Equal(p.IP))
+	})
+}
+
+func TestQueryUnmarshaler(t *testing.T) {
+	type params struct {
+		Raw     rawList    `qp:"raw"`
+		RawPtr  *rawList   `qp:"raw_ptr"`
+		Name    upper      `qp:"name"`
+		Names   []upper    `qp:"names"`
+		Missing *rawList   `qp:"missing"`
+		Lists   []*rawList `qp:"lists"`
+	}
+
+	values, err := url.ParseQuery("raw=a,b&raw=c&raw_ptr=&name=foo&name=bar&names=x,y&lists=1,2")
+	require.NoError(t, err)
+
+	var p params
+	err = Parse(values, &p)
+	require.NoError(t, err)
+
+	// Every raw value, unsplit
+	assert.Equal(t, []string{"a,b", "c"}, p.Raw.Values)
+	assert.Equal(t, &rawList{Values: []string{""}}, p.RawPtr)
+	assert.Equal(t, upper("FOO+BAR"), p.Name)
+	// Slice elements receive one split value each
+	assert.Equal(t, []upper{"X", "Y"}, p.Names)
+	assert.Nil(t, p.Missing)
+	assert.Equal(t, []*rawList{{Values: []string{"1"}}, {Values: []string{"2"}}}, p.Lists)
+}
+
+func TestUnmarshalKindCached(t *testing.T) {
+	type params struct {
+		Addr   netip.Addr    `qp:"addr"`
+		Addrs  *[]netip.Addr `qp:"addrs"`
+		IP     net.IP        `qp:"ip"`
+		Raw    *rawList      `qp:"raw"`
+		Plain  []int         `qp:"plain"`
+		Nested struct {
+			F string `qp:"f"`
+		}
+	}
+
+	d := NewDecoder()
+	info := d.getStructCache(reflect.TypeFor[params](), "")
+	require.Len(t, info.fields, 6)
+
+	kinds := make(map[string][2]unmarshalKind)
+	for _, f := range info.fields {
+		kinds[f.name] = [2]unmarshalKind{f.unmarshal, f.elemUnmarshal}
+	}
+	assert.Equal(t, [2]unmarshalKind{unmarshalText, unmarshalNone}, kinds["Addr"])
+	assert.Equal(t, [2]unmarshalKind{unmarshalNone, unmarshalText}, kinds["Addrs"])
+	assert.Equal(t, [2]unmarshalKind{unmarshalText, unmarshalNone}, kinds["IP"])
+	assert.Equal(t, [2]unmarshalKind{unmarshalQuery, unmarshalNone}, kinds["Raw"])
+	assert.Equal(t, [2]unmarshalKind{unmarshalNone, unmarshalNone}, kinds["Plain"])
+	assert.True(t, info.fields[5].isNested)
+}
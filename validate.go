@@ -0,0 +1,290 @@
+package qparser
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// rule is a validation option of a field, checked on its decoded value
+type rule struct {
+	constraint string // the option as written in the tag, e.g. "max=100"
+	check      func(v reflect.Value) error
+}
+
+// constraintError is the failure of a rule, turned into a FieldError carrying
+// the constraint by decodeState.failFrom
+type constraintError struct {
+	constraint string
+	err        error
+}
+
+func (e *constraintError) Error() string {
+	return e.err.Error()
+}
+
+func (e *constraintError) Unwrap() error {
+	return e.err
+}
+
+// isRuleOption reports whether a tag option is a validation rule
+func isRuleOption(name string) bool {
+	switch name {
+	case "min", "max", "len", "oneof", "regex", "nonzero":
+		return true
+	}
+	return false
+}
+
+// checkRules checks the decoded value fv of field against its rules, in tag
+// order, and reports the first that fails
+func (field *fieldInfo) checkRules(fv reflect.Value) error {
+	for _, r := range field.rules {
+		if err := r.check(fv); err != nil {
+			return &constraintError{constraint: r.constraint, err: fmt.Errorf("%w (%s)", err, r.constraint)}
+		}
+	}
+	return nil
+}
+
+// compileRules parses the rule options of a tag, raw being them comma
+// separated, into the rules of a field of type typ. Rules that cannot apply to
+// typ, or with a malformed argument, are rejected.
+func compileRules(typ reflect.Type, raw string) ([]rule, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var rules []rule
+	for opt := range strings.SplitSeq(raw, ",") {
+		name, arg, hasArg := strings.Cut(opt, "=")
+		switch {
+		case name == "nonzero" && hasArg:
+			return nil, fmt.Errorf("%w: option %q takes no value", ErrInvalidTag, name)
+		case name != "nonzero" && arg == "":
+			return nil, fmt.Errorf("%w: option %q requires a value", ErrInvalidTag, name)
+		}
+
+		check, err := compileRule(typ, name, arg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: option %q: %w", ErrInvalidTag, opt, err)
+		}
+		rules = append(rules, rule{constraint: opt, check: check})
+	}
+	return rules, nil
+}
+
+// compileRule returns the check of a single rule on values of type typ
+func compileRule(typ reflect.Type, name, arg string) (func(reflect.Value) error, error) {
+	if name == "nonzero" {
+		return checkNonZero, nil
+	}
+
+	// Other rules pass on nil pointers, leaving them to required and nonzero
+	elemType := typ
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	var check func(reflect.Value) error
+	var err error
+	switch name {
+	case "min", "max", "len":
+		check, err = compileBound(elemType, name, arg)
+	case "oneof":
+		check, err = forEachElem(elemType, func(t reflect.Type) (func(reflect.Value) error, error) {
+			return compileOneOf(t, strings.Split(arg, "|"))
+		})
+	case "regex":
+		check, err = forEachElem(elemType, func(t reflect.Type) (func(reflect.Value) error, error) {
+			return compileRegex(t, arg)
+		})
+	}
+	if err != nil || typ.Kind() != reflect.Ptr {
+		return check, err
+	}
+	return func(v reflect.Value) error {
+		if v.IsNil() {
+			return nil
+		}
+		return check(v.Elem())
+	}, nil
+}
+
+// checkNonZero fails on zero values, nil pointers and empty slices and maps
+func checkNonZero(v reflect.Value) error {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.Len() > 0 {
+			return nil
+		}
+	default:
+		if !v.IsZero() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: zero value", ErrEmptyValue)
+}
+
+// compileBound compiles min, max and len. They bound numbers by value, and
+// strings (in runes), slices, arrays and maps by length. len only applies to
+// lengths.
+func compileBound(typ reflect.Type, name, arg string) (func(reflect.Value) error, error) {
+	switch typ.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		bound, err := strconv.Atoi(arg)
+		if err != nil || bound < 0 {
+			return nil, fmt.Errorf("length %q is not a positive integer", arg)
+		}
+		return func(v reflect.Value) error {
+			n := v.Len()
+			if v.Kind() == reflect.String {
+				n = utf8.RuneCountInString(v.String())
+			}
+			switch {
+			case n < bound && name != "max":
+				return fmt.Errorf("%w: length %d", ErrTooSmall, n)
+			case n > bound && name != "min":
+				return fmt.Errorf("%w: length %d", ErrTooLarge, n)
+			}
+			return nil
+		}, nil
+	}
+
+	if name == "len" {
+		return nil, fmt.Errorf("not supported on %v", typ)
+	}
+	cmp, err := compileCompare(typ, arg)
+	if err != nil {
+		return nil, err
+	}
+	return func(v reflect.Value) error {
+		switch c := cmp(v); {
+		case c < 0 && name == "min":
+			return fmt.Errorf("%w: %v", ErrTooSmall, v)
+		case c > 0 && name == "max":
+			return fmt.Errorf("%w: %v", ErrTooLarge, v)
+		}
+		return nil
+	}, nil
+}
+
+// compileCompare parses arg as a number of typ's kind and returns a function
+// comparing values of typ to it, like cmp.Compare
+func compileCompare(typ reflect.Type, arg string) (func(reflect.Value) int, error) {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(arg, 10, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %v", arg, typ)
+		}
+		return func(v reflect.Value) int { return cmpNumber(v.Int(), n) }, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(arg, 10, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %v", arg, typ)
+		}
+		return func(v reflect.Value) int { return cmpNumber(v.Uint(), n) }, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(arg, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %v", arg, typ)
+		}
+		return func(v reflect.Value) int { return cmpNumber(v.Float(), f) }, nil
+	}
+	return nil, fmt.Errorf("not supported on %v", typ)
+}
+
+func cmpNumber[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compileOneOf checks that values of typ, a string or number kind, equal one
+// of allowed
+func compileOneOf(typ reflect.Type, allowed []string) (func(reflect.Value) error, error) {
+	if typ.Kind() == reflect.String {
+		return func(v reflect.Value) error {
+			if !slices.Contains(allowed, v.String()) {
+				return fmt.Errorf("%w: %q", ErrNotAllowed, v.String())
+			}
+			return nil
+		}, nil
+	}
+
+	cmps := make([]func(reflect.Value) int, len(allowed))
+	for i, a := range allowed {
+		cmp, err := compileCompare(typ, a)
+		if err != nil {
+			return nil, err
+		}
+		cmps[i] = cmp
+	}
+	return func(v reflect.Value) error {
+		for _, cmp := range cmps {
+			if cmp(v) == 0 {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %v", ErrNotAllowed, v)
+	}, nil
+}
+
+// compileRegex checks that values of typ, a string kind, match pattern
+func compileRegex(typ reflect.Type, pattern string) (func(reflect.Value) error, error) {
+	if typ.Kind() != reflect.String {
+		return nil, fmt.Errorf("not supported on %v", typ)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(v reflect.Value) error {
+		if !re.MatchString(v.String()) {
+			return fmt.Errorf("%w: %q", ErrPatternMismatch, v.String())
+		}
+		return nil
+	}, nil
+}
+
+// forEachElem compiles a rule on the elements of typ when it is a slice, or
+// on typ itself otherwise. Nil pointer elements are skipped.
+func forEachElem(typ reflect.Type, compile func(reflect.Type) (func(reflect.Value) error, error)) (func(reflect.Value) error, error) {
+	if typ.Kind() != reflect.Slice {
+		return compile(typ)
+	}
+	elemType := typ.Elem()
+	ptr := elemType.Kind() == reflect.Ptr
+	if ptr {
+		elemType = elemType.Elem()
+	}
+	check, err := compile(elemType)
+	if err != nil {
+		return nil, err
+	}
+	return func(v reflect.Value) error {
+		for i := range v.Len() {
+			elem := v.Index(i)
+			if ptr {
+				if elem.IsNil() {
+					continue
+				}
+				elem = elem.Elem()
+			}
+			if err := check(elem); err != nil {
+				return fmt.Errorf("element [%d]: %w", i, err)
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,169 @@
+package qparser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listParams struct {
+	Page    int           `qp:"page,default=1,min=1"`
+	Limit   uint8         `qp:"limit,max=100"`
+	Ratio   *float64      `qp:"ratio,min=0,max=1"`
+	Query   string        `qp:"q,min=2,max=5"`
+	Code    string        `qp:"code,len=3,regex=^[A-Z]+$"`
+	Sort    string        `qp:"sort,oneof=asc|desc"`
+	Sizes   []int         `qp:"sizes,max=3,oneof=8|16|32"`
+	Tags    []string      `qp:"tags,regex=^[a-z]+$"`
+	Timeout time.Duration `qp:"timeout,max=60000000000"`
+	Cursor  string        `qp:"cursor,nonzero"`
+}
+
+func TestValidation(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		var got listParams
+		require.NoError(t, Parse(url.Values{
+			"limit":   {"100"},
+			"ratio":   {"0.5"},
+			"q":       {"héllo"},
+			"code":    {"ABC"},
+			"sort":    {"desc"},
+			"sizes":   {"8,32"},
+			"tags":    {"go,web"},
+			"timeout": {"30000000000"},
+			"cursor":  {"x"},
+		}, &got))
+		assert.Equal(t, 1, got.Page)
+		assert.Equal(t, []int{8, 32}, got.Sizes)
+
+		got = listParams{}
+		require.NoError(t, Parse(url.Values{}, &got), "rules only check the values sent")
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			values     url.Values
+			field      string
+			constraint string
+			err        error
+			msg        string
+		}{
+			{"Min", url.Values{"page": {"0"}}, "Page", "min=1", ErrTooSmall, "too small: 0 (min=1)"},
+			{"Max-Uint", url.Values{"limit": {"101"}}, "Limit", "max=100", ErrTooLarge, "too large: 101 (max=100)"},
+			{"Pointer", url.Values{"ratio": {"1.5"}}, "Ratio", "max=1", ErrTooLarge, "too large: 1.5 (max=1)"},
+			{"String-Min", url.Values{"q": {"é"}}, "Query", "min=2", ErrTooSmall, "too small: length 1 (min=2)"},
+			{"String-Max", url.Values{"q": {"abcdef"}}, "Query", "max=5", ErrTooLarge, "too large: length 6 (max=5)"},
+			{"Len", url.Values{"code": {"AB"}}, "Code", "len=3", ErrTooSmall, "too small: length 2 (len=3)"},
+			{"Regex", url.Values{"code": {"abc"}}, "Code", "regex=^[A-Z]+$", ErrPatternMismatch, `pattern mismatch: "abc" (regex=^[A-Z]+$)`},
+			{"OneOf", url.Values{"sort": {"up"}}, "Sort", "oneof=asc|desc", ErrNotAllowed, `not allowed: "up" (oneof=asc|desc)`},
+			{"Slice-Len", url.Values{"sizes": {"8,8,8,8"}}, "Sizes", "max=3", ErrTooLarge, "too large: length 4 (max=3)"},
+			{"Slice-OneOf", url.Values{"sizes": {"8,12"}}, "Sizes", "oneof=8|16|32", ErrNotAllowed, "element [1]: not allowed: 12 (oneof=8|16|32)"},
+			{"Slice-Regex", url.Values{"tags": {"go", "C++"}}, "Tags", "regex=^[a-z]+$", ErrPatternMismatch, `element [1]: pattern mismatch: "C++" (regex=^[a-z]+$)`},
+			{"Duration", url.Values{"timeout": {"90000000000"}}, "Timeout", "max=60000000000", ErrTooLarge, "too large: 1m30s (max=60000000000)"},
+			{"NonZero", url.Values{"cursor": {""}}, "Cursor", "nonzero", ErrEmptyValue, "empty value: zero value (nonzero)"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got listParams
+				err := Parse(tt.values, &got)
+				assert.ErrorIs(t, err, tt.err)
+
+				var fieldErr *FieldError
+				require.ErrorAs(t, err, &fieldErr)
+				assert.Equal(t, "listParams."+tt.field, fieldErr.FieldName)
+				assert.Equal(t, tt.constraint, fieldErr.Constraint)
+				assert.EqualError(t, fieldErr.Err, tt.msg)
+			})
+		}
+	})
+
+	t.Run("Decode-Error-Has-No-Constraint", func(t *testing.T) {
+		var got listParams
+		err := Parse(url.Values{"page": {"x"}}, &got)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.Empty(t, fieldErr.Constraint)
+	})
+
+	t.Run("All-Errors", func(t *testing.T) {
+		var got listParams
+		err := ParseAll(url.Values{"page": {"0"}, "sort": {"up"}, "q": {"ok"}}, &got)
+
+		var fieldErrs FieldErrors
+		require.ErrorAs(t, err, &fieldErrs)
+		require.Len(t, fieldErrs, 2)
+		assert.Equal(t, "min=1", fieldErrs[0].Constraint)
+		assert.Equal(t, "oneof=asc|desc", fieldErrs[1].Constraint)
+		assert.Equal(t, "ok", got.Query)
+	})
+
+	t.Run("Bound-Field", func(t *testing.T) {
+		type params struct {
+			Tenant string `header:"X-Tenant,regex=^[a-z]+$"`
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant", "ACME")
+
+		var got params
+		err := Bind(req, &got)
+		assert.ErrorIs(t, err, ErrPatternMismatch)
+		assert.EqualError(t, err, `failed to parse "params.Tenant" from header: pattern mismatch: "ACME" (regex=^[a-z]+$)`)
+	})
+
+	t.Run("Invalid-Rules", func(t *testing.T) {
+		tests := []struct {
+			name string
+			dst  any
+		}{
+			{"Bad-Number", &struct {
+				N int `qp:"n,min=abc"`
+			}{}},
+			{"Out-Of-Range", &struct {
+				N int8 `qp:"n,max=300"`
+			}{}},
+			{"Negative-Length", &struct {
+				S string `qp:"s,min=-1"`
+			}{}},
+			{"Len-On-Number", &struct {
+				N int `qp:"n,len=2"`
+			}{}},
+			{"Regex-On-Number", &struct {
+				N int `qp:"n,regex=^1$"`
+			}{}},
+			{"Bad-Regex", &struct {
+				S string `qp:"s,regex=["`
+			}{}},
+			{"Min-On-Bool", &struct {
+				B bool `qp:"b,min=1"`
+			}{}},
+			{"OneOf-Bad-Number", &struct {
+				N int `qp:"n,oneof=1|two"`
+			}{}},
+			{"Missing-Value", &struct {
+				N int `qp:"n,min"`
+			}{}},
+			{"NonZero-Value", &struct {
+				N int `qp:"n,nonzero=1"`
+			}{}},
+			{"Map-Field", &struct {
+				M map[string]int `qp:"m,max=1"`
+			}{}},
+			{"Default-Violates", &struct {
+				N int `qp:"n,default=0,min=1"`
+			}{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.ErrorIs(t, Parse(url.Values{}, tt.dst), ErrInvalidTag)
+			})
+		}
+	})
+}